@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,14 +15,16 @@ import (
 	"blog/internal/database"
 	"blog/internal/handlers"
 	"blog/internal/middleware"
-	"blog/internal/models"
 	"blog/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	db, err := database.NewGormDB(&cfg.Database)
 	if err != nil {
@@ -33,14 +37,11 @@ func main() {
 	}
 	defer sqlDB.Close()
 
-	if err := db.AutoMigrate(&models.Post{}, &models.ActivityLog{}); err != nil {
+	if err := database.Migrate(db); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	redis, err := database.NewRedisClient(&cfg.Redis)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
-	}
+	redis := database.NewRedisClient(&cfg.Redis)
 	defer redis.Close()
 
 	es, err := database.NewElasticsearchClient(&cfg.Elasticsearch)
@@ -48,25 +49,99 @@ func main() {
 		log.Fatalf("Failed to connect to Elasticsearch: %v", err)
 	}
 
+	uow := database.NewUnitOfWork(db)
+	postRepository := database.NewPostRepository(db)
+	activityLogRepository := database.NewActivityLogRepository(db)
 	cacheService := services.NewCacheService(redis)
-	searchService := services.NewSearchService(es)
-	activityService := services.NewActivityService()
-	postService := services.NewPostService(db, cacheService, searchService, activityService)
+	searchService := services.NewSearchService(es, cfg.Elasticsearch.RefreshPolicy)
+	activityService := services.NewActivityService(activityLogRepository)
+	webhookService := services.NewWebhookService(database.NewWebhookRepository(db))
+	eventStream := services.NewEventStreamService(cacheService)
+
+	// Transactional subscribers commit or roll back with the post; the rest
+	// run once the change is durable.
+	events := services.NewEventBus()
+	events.SubscribeTx(activityService.HandlePostEvent)
+	events.SubscribeTx(webhookService.HandlePostEvent)
+	events.Subscribe(services.InvalidateCachedPosts(cacheService))
+	var postIndex services.PostIndex = searchService
+	var bulkIndexer *services.BulkIndexer
+	if cfg.Elasticsearch.BulkFlushInterval > 0 {
+		bulkIndexer = services.NewBulkIndexer(searchService, cfg.Elasticsearch.BulkFlushSize, cfg.Elasticsearch.BulkMaxPending)
+		postIndex = bulkIndexer
+	}
+	events.SubscribeBatch(services.SyncSearchIndex(postIndex))
+	events.Subscribe(eventStream.HandlePostEvent)
+	feedService := services.NewFeedService(postRepository, cacheService, cfg.Site.FeedSize)
+	events.Subscribe(feedService.HandlePostEvent)
+	sitemapService := services.NewSitemapService(postRepository, &cfg.Site)
+	events.Subscribe(sitemapService.HandlePostEvent)
+	events.Subscribe(services.RecordPostEventMetrics)
+
+	postService := services.NewPostService(uow, postRepository, cacheService, events)
+	viewService := services.NewViewService(uow, postRepository, database.NewPostStatsRepository(db), cacheService, postIndex, activityService, cfg.Views.TrackUniqueVisitors)
 
 	ctx := context.Background()
 	if err := searchService.InitializeIndex(ctx); err != nil {
 		log.Fatalf("Failed to initialize Elasticsearch index: %v", err)
 	}
+	if err := searchService.UpgradeIndex(ctx, postRepository); err != nil {
+		log.Fatalf("Failed to upgrade Elasticsearch index: %v", err)
+	}
+
+	retentionService := services.NewRetentionService(uow, activityLogRepository, &cfg.Activity)
+	analyticsService := services.NewAnalyticsService(database.NewAnalyticsRepository(db), cacheService)
+
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Add(5)
+	go func() {
+		defer workers.Done()
+		viewService.Run(workerCtx, cfg.Views.FlushInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		retentionService.Run(workerCtx, cfg.Activity.MaintenanceInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		webhookService.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		eventStream.Run(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		sitemapService.Run(workerCtx, cfg.Site.SitemapRegenerateDelay, cfg.Site.SitemapRefreshInterval)
+	}()
+	if bulkIndexer != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			bulkIndexer.Run(workerCtx, cfg.Elasticsearch.BulkFlushInterval)
+		}()
+	}
 
-	postHandler := handlers.NewPostHandler(postService)
-	searchHandler := handlers.NewSearchHandler(searchService)
+	postHandler := handlers.NewPostHandler(postService, viewService, &cfg.Posts)
+	searchHandler := handlers.NewSearchHandler(searchService, &cfg.Posts)
+	activityHandler := handlers.NewActivityHandler(activityService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	eventHandler := handlers.NewEventHandler(eventStream)
+	feedHandler := handlers.NewFeedHandler(feedService, &cfg.Site, &cfg.Posts)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapService, &cfg.Site)
+	healthHandler := handlers.NewHealthHandler(cacheService)
 
-	router := setupRouter(postHandler, searchHandler)
+	router := setupRouter(cfg, postHandler, searchHandler, activityHandler, analyticsHandler, webhookHandler, eventHandler, feedHandler, sitemapHandler, healthHandler)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
 		Handler: router,
 	}
+	// SSE responses never finish on their own; end them so Shutdown can
+	// drain the remaining connections.
+	srv.RegisterOnShutdown(eventStream.Close)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -87,31 +162,68 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	stopWorkers()
+	workers.Wait()
+
 	log.Println("Server exited")
 }
 
-func setupRouter(postHandler *handlers.PostHandler, searchHandler *handlers.SearchHandler) *gin.Engine {
+func setupRouter(cfg *config.Config, postHandler *handlers.PostHandler, searchHandler *handlers.SearchHandler, activityHandler *handlers.ActivityHandler, analyticsHandler *handlers.AnalyticsHandler, webhookHandler *handlers.WebhookHandler, eventHandler *handlers.EventHandler, feedHandler *handlers.FeedHandler, sitemapHandler *handlers.SitemapHandler, healthHandler *handlers.HealthHandler) *gin.Engine {
 	router := gin.New()
 
+	router.Use(middleware.RequestMetaMiddleware())
 	router.Use(middleware.LoggerMiddleware())
 	router.Use(middleware.RecoveryMiddleware())
+	router.Use(middleware.ErrorMiddleware())
 	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.AuthMiddleware(cfg.Auth.AdminAPIKeys))
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	router.GET("/health", healthHandler.Health)
+
+	// Syndication feeds
+	router.GET("/feed.rss", feedHandler.RSS)
+	router.GET("/feed.atom", feedHandler.Atom)
+	router.GET("/feed.json", feedHandler.JSON)
+	router.GET("/tags/:tag/feed.rss", feedHandler.RSS)
+	router.GET("/tags/:tag/feed.atom", feedHandler.Atom)
+	router.GET("/tags/:tag/feed.json", feedHandler.JSON)
+
+	// Sitemaps
+	router.GET("/sitemap.xml", sitemapHandler.Index)
+	router.GET("/sitemaps/:file", sitemapHandler.Chunk)
 
 	api := router.Group("/api/v1")
 	{
 		// Posts endpoints
 		api.POST("/posts", postHandler.CreatePost)
+		api.POST("/posts/_bulk", postHandler.BulkPosts)
 		api.GET("/posts/:id", postHandler.GetPost)
 		api.PUT("/posts/:id", postHandler.UpdatePost)
+		api.PATCH("/posts/:id", postHandler.PatchPost)
 		api.DELETE("/posts/:id", postHandler.DeletePost)
 
 		// Search endpoints
 		api.GET("/posts/search", searchHandler.SearchPosts)
 		api.GET("/posts/search-by-tag", postHandler.SearchPostsByTag)
+
+		// Live post events (Server-Sent Events)
+		api.GET("/events", eventHandler.StreamEvents)
+
+		// Admin endpoints
+		admin := api.Group("", middleware.RequireAdmin())
+		admin.GET("/activity", activityHandler.ListActivity)
+		admin.GET("/posts/:id/activity", activityHandler.ListPostActivity)
+		admin.GET("/posts/export", postHandler.ExportPosts)
+		admin.POST("/posts/import", postHandler.ImportPosts)
+		admin.GET("/analytics/posts/daily", analyticsHandler.DailyPostActivity)
+		admin.GET("/analytics/posts/most-edited", analyticsHandler.MostEditedPosts)
+		admin.GET("/analytics/posts/most-viewed", analyticsHandler.MostViewedPosts)
+		admin.GET("/analytics/tags/trends", analyticsHandler.TagTrends)
+		admin.POST("/webhooks", webhookHandler.CreateWebhook)
+		admin.GET("/webhooks", webhookHandler.ListWebhooks)
+		admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
 	}
 
 	return router
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +13,11 @@ type Config struct {
 	Redis         RedisConfig
 	Elasticsearch ElasticsearchConfig
 	Server        ServerConfig
+	Views         ViewsConfig
+	Auth          AuthConfig
+	Activity      ActivityConfig
+	Posts         PostsConfig
+	Site          SiteConfig
 }
 
 type DatabaseConfig struct {
@@ -28,14 +36,97 @@ type RedisConfig struct {
 
 type ElasticsearchConfig struct {
 	URL string
+	// RefreshPolicy is passed as ?refresh= on writes: "false", "wait_for"
+	// or "true".
+	RefreshPolicy string
+	// BulkFlushInterval buffers post writes and sends them in bulk at most
+	// this long after they happen, or once BulkFlushSize are pending. Zero
+	// writes every change through immediately.
+	BulkFlushInterval time.Duration
+	BulkFlushSize     int
+	// BulkMaxPending caps the buffered posts while Elasticsearch is down.
+	BulkMaxPending int
 }
 
 type ServerConfig struct {
 	Port string
 }
 
-func Load() *Config {
-	return &Config{
+type AuthConfig struct {
+	// AdminAPIKeys maps an actor name to its API key.
+	AdminAPIKeys map[string]string
+}
+
+type ActivityConfig struct {
+	// Retention overrides DefaultRetention per action. Zero keeps logs
+	// forever.
+	Retention           map[string]time.Duration
+	DefaultRetention    time.Duration
+	ArchiveDir          string
+	MaintenanceInterval time.Duration
+}
+
+type PostsConfig struct {
+	// RequireIfMatch rejects PUT, PATCH and DELETE requests without an If-Match
+	// header with 428 instead of applying them unconditionally.
+	RequireIfMatch bool
+	// CacheControl and SearchCacheControl are sent on single post and search
+	// responses. Empty sends no Cache-Control header.
+	CacheControl       string
+	SearchCacheControl string
+	// SurrogateKeyHeader names the header listing the keys a reverse proxy
+	// can purge cached responses by, e.g. "Surrogate-Key" or "Cache-Tag".
+	// Empty disables it.
+	SurrogateKeyHeader string
+	// BulkMaxOperations caps the number of lines in a bulk request.
+	BulkMaxOperations int
+}
+
+type SiteConfig struct {
+	// BaseURL is the public origin of the site, used for absolute links in
+	// feeds.
+	BaseURL     string
+	Title       string
+	Description string
+	// PostPath is the path of a post's public page; "{id}" and "{slug}" are
+	// replaced with the post's values.
+	PostPath string
+	// FeedSize is the number of posts in each feed.
+	FeedSize         int
+	FeedCacheControl string
+	// SitemapChunkSize is the number of posts per sitemap, at most 50000.
+	SitemapChunkSize int
+	// The sitemap is rebuilt SitemapRegenerateDelay after a post changes
+	// and every SitemapRefreshInterval for changes made on other instances.
+	SitemapRegenerateDelay time.Duration
+	SitemapRefreshInterval time.Duration
+	SitemapCacheControl    string
+}
+
+// PostURL returns the absolute URL of a post's public page.
+func (c *SiteConfig) PostURL(id, slug string) string {
+	path := strings.NewReplacer("{id}", id, "{slug}", slug).Replace(c.PostPath)
+	return strings.TrimSuffix(c.BaseURL, "/") + path
+}
+
+type ViewsConfig struct {
+	FlushInterval       time.Duration
+	TrackUniqueVisitors bool
+}
+
+// Load reads the configuration from the environment and rejects values the
+// server cannot run with.
+func Load() (*Config, error) {
+	retention, err := parseRetention(getEnv("ACTIVITY_RETENTION", "view_post=90d"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACTIVITY_RETENTION: %w", err)
+	}
+	defaultRetention, err := parseRetentionPeriod(getEnv("ACTIVITY_RETENTION_DEFAULT", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACTIVITY_RETENTION_DEFAULT: %w", err)
+	}
+
+	cfg := &Config{
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
@@ -49,12 +140,62 @@ func Load() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 		},
 		Elasticsearch: ElasticsearchConfig{
-			URL: getEnv("ELASTICSEARCH_URL", "http://localhost:9200"),
+			URL:               getEnv("ELASTICSEARCH_URL", "http://localhost:9200"),
+			RefreshPolicy:     getEnv("ELASTICSEARCH_REFRESH", "false"),
+			BulkFlushInterval: getEnvDuration("ELASTICSEARCH_BULK_FLUSH_INTERVAL", time.Second),
+			BulkFlushSize:     getEnvInt("ELASTICSEARCH_BULK_FLUSH_SIZE", 500),
+			BulkMaxPending:    getEnvInt("ELASTICSEARCH_BULK_MAX_PENDING", 50000),
 		},
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 		},
+		Views: ViewsConfig{
+			FlushInterval:       getEnvDuration("VIEWS_FLUSH_INTERVAL", time.Minute),
+			TrackUniqueVisitors: getEnvBool("VIEWS_TRACK_UNIQUE_VISITORS", true),
+		},
+		Auth: AuthConfig{
+			AdminAPIKeys: parseAPIKeys(getEnv("ADMIN_API_KEYS", "")),
+		},
+		Activity: ActivityConfig{
+			Retention:           retention,
+			DefaultRetention:    defaultRetention,
+			ArchiveDir:          getEnv("ACTIVITY_ARCHIVE_DIR", "./archive/activity_logs"),
+			MaintenanceInterval: getEnvDuration("ACTIVITY_MAINTENANCE_INTERVAL", time.Hour),
+		},
+		Posts: PostsConfig{
+			RequireIfMatch:     getEnvBool("POSTS_REQUIRE_IF_MATCH", false),
+			CacheControl:       getEnv("POSTS_CACHE_CONTROL", "public, max-age=60"),
+			SearchCacheControl: getEnv("POSTS_SEARCH_CACHE_CONTROL", "public, max-age=30"),
+			SurrogateKeyHeader: getEnv("POSTS_SURROGATE_KEY_HEADER", "Surrogate-Key"),
+			BulkMaxOperations:  getEnvInt("POSTS_BULK_MAX_OPERATIONS", 1000),
+		},
+		Site: SiteConfig{
+			BaseURL:                getEnv("SITE_BASE_URL", "http://localhost:8080"),
+			Title:                  getEnv("SITE_TITLE", "Blog"),
+			Description:            getEnv("SITE_DESCRIPTION", ""),
+			PostPath:               getEnv("SITE_POST_PATH", "/api/v1/posts/{id}"),
+			FeedSize:               getEnvInt("FEED_SIZE", 20),
+			FeedCacheControl:       getEnv("FEED_CACHE_CONTROL", "public, max-age=300"),
+			SitemapChunkSize:       getEnvInt("SITEMAP_CHUNK_SIZE", 50000),
+			SitemapRegenerateDelay: getEnvDuration("SITEMAP_REGENERATE_DELAY", 10*time.Second),
+			SitemapRefreshInterval: getEnvDuration("SITEMAP_REFRESH_INTERVAL", time.Hour),
+			SitemapCacheControl:    getEnv("SITEMAP_CACHE_CONTROL", "public, max-age=3600"),
+		},
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Views.FlushInterval <= 0 {
+		return fmt.Errorf("VIEWS_FLUSH_INTERVAL must be positive, got %s", c.Views.FlushInterval)
+	}
+	if c.Activity.MaintenanceInterval <= 0 {
+		return fmt.Errorf("ACTIVITY_MAINTENANCE_INTERVAL must be positive, got %s", c.Activity.MaintenanceInterval)
+	}
+	return nil
 }
 
 func (c *DatabaseConfig) DSN() string {
@@ -71,4 +212,88 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// parseAPIKeys reads "name:key" pairs separated by commas.
+func parseAPIKeys(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" {
+			continue
+		}
+		keys[name] = key
+	}
+	return keys
+}
+
+// parseRetention reads "action=period" pairs separated by commas, e.g.
+// "view_post=30d,update_post=365d".
+func parseRetention(value string) (map[string]time.Duration, error) {
+	retention := make(map[string]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		action, period, ok := strings.Cut(pair, "=")
+		if !ok || action == "" {
+			return nil, fmt.Errorf("expected action=period, got %q", pair)
+		}
+		d, err := parseRetentionPeriod(period)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", action, err)
+		}
+		retention[action] = d
+	}
+	return retention, nil
+}
+
+// parseRetentionPeriod accepts a number of days ("30d") or a Go duration.
+// Zero means no expiry.
+func parseRetentionPeriod(value string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention period %q", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid retention period %q", value)
+		}
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("retention period %q is negative", value)
+	}
+	return d, nil
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	got, err := parseRetention("view_post=30d, update_post=12h,create_post=0,")
+	if err != nil {
+		t.Fatalf("parseRetention: %v", err)
+	}
+	want := map[string]time.Duration{
+		"view_post":   30 * 24 * time.Hour,
+		"update_post": 12 * time.Hour,
+		"create_post": 0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for action, period := range want {
+		if got[action] != period {
+			t.Errorf("%s = %s, want %s", action, got[action], period)
+		}
+	}
+
+	for _, value := range []string{"view_post=90dd", "view_post", "view_post=-1d", "view_post=soon"} {
+		if _, err := parseRetention(value); err == nil {
+			t.Errorf("parseRetention(%q) accepted an invalid period", value)
+		}
+	}
+}
+
+func TestLoadRejectsNonPositiveIntervals(t *testing.T) {
+	for _, key := range []string{"VIEWS_FLUSH_INTERVAL", "ACTIVITY_MAINTENANCE_INTERVAL"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, "0s")
+			if _, err := Load(); err == nil {
+				t.Errorf("Load accepted %s=0s", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"blog/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// activity_logs is range partitioned by month on logged_at. Partitions are
+// named activity_logs_yYYYYmMM so their bounds can be recovered from the name.
+const (
+	activityLogsTable          = "activity_logs"
+	activityPartitionPrefix    = "activity_logs_y"
+	activityPartitionLayout    = "2006m01"
+	activityPartitionsAhead    = 2
+	unpartitionedActivityTable = "activity_logs_unpartitioned"
+)
+
+type ActivityLogPartition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+func activityPartitionFor(t time.Time) ActivityLogPartition {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return ActivityLogPartition{
+		Name:  activityPartitionPrefix + start.Format(activityPartitionLayout),
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+	}
+}
+
+// partitionActivityLogs converts a plain activity_logs table, as created by
+// AutoMigrate, into a partitioned one. It is a no-op once converted.
+func partitionActivityLogs(db *gorm.DB) error {
+	var partitioned bool
+	if err := db.Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_partitioned_table
+			WHERE partrelid = 'activity_logs'::regclass
+		)`).Scan(&partitioned).Error; err != nil {
+		return fmt.Errorf("failed to inspect activity_logs: %w", err)
+	}
+	if partitioned {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var bounds struct {
+			Min *time.Time
+			Max *time.Time
+		}
+		if err := tx.Raw("SELECT MIN(logged_at) AS min, MAX(logged_at) AS max FROM activity_logs").Scan(&bounds).Error; err != nil {
+			return err
+		}
+
+		from, to := time.Now().UTC(), time.Now().UTC()
+		if bounds.Min != nil && bounds.Min.Before(from) {
+			from = *bounds.Min
+		}
+		if bounds.Max != nil && bounds.Max.After(to) {
+			to = *bounds.Max
+		}
+
+		stmts := []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", activityLogsTable, unpartitionedActivityTable),
+			fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS) PARTITION BY RANGE (logged_at)",
+				activityLogsTable, unpartitionedActivityTable),
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := createActivityPartitions(tx, from, to.AddDate(0, activityPartitionsAhead, 0)); err != nil {
+			return err
+		}
+
+		// The old table's primary key index holds the activity_logs_pkey
+		// name, so it has to go before the new key is added.
+		stmts = []string{
+			fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", activityLogsTable, unpartitionedActivityTable),
+			fmt.Sprintf("DROP TABLE %s", unpartitionedActivityTable),
+			fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id, logged_at)", activityLogsTable),
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+
+		// Recreate the secondary indexes that were dropped with the old table.
+		if err := tx.AutoMigrate(&models.ActivityLog{}); err != nil {
+			return err
+		}
+		return (&models.ActivityLog{}).AfterAutoMigrate(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to partition activity_logs: %w", err)
+	}
+
+	log.Println("Converted activity_logs to a partitioned table")
+	return nil
+}
+
+// EnsureActivityLogPartitions creates the monthly partitions covering
+// [from, now + activityPartitionsAhead months].
+func EnsureActivityLogPartitions(db *gorm.DB, from time.Time) error {
+	return createActivityPartitions(db, from, time.Now().UTC().AddDate(0, activityPartitionsAhead, 0))
+}
+
+func createActivityPartitions(db *gorm.DB, from, to time.Time) error {
+	for p := activityPartitionFor(from); !p.Start.After(to); p = activityPartitionFor(p.End) {
+		stmt := fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			p.Name, activityLogsTable,
+			p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339),
+		)
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// EnsurePartitions creates the monthly partitions from the one holding from
+// up to activityPartitionsAhead months ahead.
+func (r *ActivityLogRepository) EnsurePartitions(ctx context.Context, from time.Time) error {
+	return EnsureActivityLogPartitions(Conn(ctx, r.db), from)
+}
+
+// Partitions returns the monthly partitions, oldest first.
+func (r *ActivityLogRepository) Partitions(ctx context.Context) ([]ActivityLogPartition, error) {
+	var names []string
+	if err := Conn(ctx, r.db).Raw(`
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'activity_logs'::regclass
+		ORDER BY c.relname`).Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list activity_logs partitions: %w", err)
+	}
+
+	partitions := make([]ActivityLogPartition, 0, len(names))
+	for _, name := range names {
+		start, err := time.Parse(activityPartitionLayout, strings.TrimPrefix(name, activityPartitionPrefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, activityPartitionFor(start))
+	}
+
+	return partitions, nil
+}
+
+// EachInPartition calls fn for every entry of the partition, oldest first.
+func (r *ActivityLogRepository) EachInPartition(ctx context.Context, p ActivityLogPartition, fn func(entry *models.ActivityLog) error) error {
+	db := Conn(ctx, r.db)
+	rows, err := db.Table(p.Name).Order("logged_at, id").Rows()
+	if err != nil {
+		return err
+	}
+	return eachActivityLog(db, rows, fn)
+}
+
+func (r *ActivityLogRepository) DropPartition(ctx context.Context, p ActivityLogPartition) error {
+	if err := Conn(ctx, r.db).Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", p.Name)).Error; err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", p.Name, err)
+	}
+	return nil
+}
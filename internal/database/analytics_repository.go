@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"blog/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsRepository aggregates activity_logs and post_stats for the
+// analytics reports. Windows are [from, to).
+const statsDayLayout = "2006-01-02"
+
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// DailyPostActivity counts post creations, updates and deletions per day,
+// with a row for every day of the window.
+func (r *AnalyticsRepository) DailyPostActivity(ctx context.Context, from, to time.Time) ([]models.DailyPostActivity, error) {
+	var result []models.DailyPostActivity
+	err := Conn(ctx, r.db).Raw(`
+		SELECT d.day::date AS day,
+			COUNT(a.id) FILTER (WHERE a.action = ?) AS created,
+			COUNT(a.id) FILTER (WHERE a.action = ?) AS updated,
+			COUNT(a.id) FILTER (WHERE a.action = ?) AS deleted
+		FROM generate_series(date_trunc('day', ?::timestamptz), date_trunc('day', ?::timestamptz - interval '1 microsecond'), interval '1 day') AS d(day)
+		LEFT JOIN activity_logs a
+			ON a.logged_at >= d.day AND a.logged_at < d.day + interval '1 day'
+			AND a.logged_at >= ? AND a.logged_at < ?
+			AND a.action IN (?, ?, ?)
+		GROUP BY d.day
+		ORDER BY d.day`,
+		models.ActionCreatePost, models.ActionUpdatePost, models.ActionDeletePost,
+		from, to,
+		from, to,
+		models.ActionCreatePost, models.ActionUpdatePost, models.ActionDeletePost,
+	).Scan(&result).Error
+	return result, err
+}
+
+// MostEditedPosts ranks posts by their update entries, labelled with the
+// title of the latest one.
+func (r *AnalyticsRepository) MostEditedPosts(ctx context.Context, from, to time.Time, limit int) ([]models.PostRanking, error) {
+	var result []models.PostRanking
+	err := Conn(ctx, r.db).Raw(`
+		SELECT post_id::text AS post_id,
+			(ARRAY_AGG(post_title ORDER BY logged_at DESC))[1] AS title,
+			COUNT(*) AS count,
+			RANK() OVER (ORDER BY COUNT(*) DESC) AS rank
+		FROM activity_logs
+		WHERE action = ? AND logged_at >= ? AND logged_at < ?
+		GROUP BY post_id
+		ORDER BY count DESC, post_id
+		LIMIT ?`,
+		models.ActionUpdatePost, from, to, limit,
+	).Scan(&result).Error
+	return result, err
+}
+
+// MostViewedPosts ranks posts by views on the UTC days the window touches.
+func (r *AnalyticsRepository) MostViewedPosts(ctx context.Context, from, to time.Time, limit int) ([]models.PostRanking, error) {
+	firstDay, endDay := statsDays(from, to)
+	var result []models.PostRanking
+	err := Conn(ctx, r.db).Raw(`
+		SELECT s.post_id::text AS post_id,
+			p.title,
+			SUM(s.views)::bigint AS count,
+			RANK() OVER (ORDER BY SUM(s.views) DESC) AS rank
+		FROM post_stats s
+		JOIN posts p ON p.id = s.post_id
+		WHERE s.day >= ?::date AND s.day < ?::date
+		GROUP BY s.post_id, p.title
+		ORDER BY count DESC, s.post_id
+		LIMIT ?`,
+		firstDay, endDay, limit,
+	).Scan(&result).Error
+	return result, err
+}
+
+// TagTrends returns the top limit tags by views for each UTC day the window
+// touches, with the change from the tag's previous day.
+func (r *AnalyticsRepository) TagTrends(ctx context.Context, from, to time.Time, limit int) ([]models.TagTrend, error) {
+	firstDay, endDay := statsDays(from, to)
+	var result []models.TagTrend
+	err := Conn(ctx, r.db).Raw(`
+		WITH daily AS (
+			SELECT s.day, t.tag, SUM(s.views)::bigint AS views
+			FROM post_stats s
+			JOIN posts p ON p.id = s.post_id
+			CROSS JOIN LATERAL unnest(p.tags) AS t(tag)
+			WHERE s.day >= ?::date AND s.day < ?::date
+			GROUP BY s.day, t.tag
+		), ranked AS (
+			SELECT day, tag, views,
+				views - LAG(views, 1, 0::bigint) OVER (PARTITION BY tag ORDER BY day) AS change,
+				RANK() OVER (PARTITION BY day ORDER BY views DESC) AS rank
+			FROM daily
+		)
+		SELECT day, tag, views, change, rank
+		FROM ranked
+		WHERE rank <= ?
+		ORDER BY day, rank, tag`,
+		firstDay, endDay, limit,
+	).Scan(&result).Error
+	return result, err
+}
+
+// statsDays converts the window [from, to) to the post_stats days it
+// touches, as a first day and an exclusive end day. post_stats days are UTC
+// dates; they are passed as literals so the session TimeZone does not shift
+// them.
+func statsDays(from, to time.Time) (string, string) {
+	first := from.UTC().Truncate(24 * time.Hour)
+	end := to.UTC().Add(-time.Nanosecond).Truncate(24*time.Hour).AddDate(0, 0, 1)
+	return first.Format(statsDayLayout), end.Format(statsDayLayout)
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsDays(t *testing.T) {
+	ict := time.FixedZone("ICT", 7*60*60)
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		wantFirst string
+		wantEnd   string
+	}{
+		{
+			name:      "midnight bounds",
+			from:      time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			to:        time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			wantFirst: "2026-10-01",
+			wantEnd:   "2026-10-19",
+		},
+		{
+			name:      "to just after midnight",
+			from:      time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			to:        time.Date(2026, 10, 19, 0, 0, 0, 1, time.UTC),
+			wantFirst: "2026-10-01",
+			wantEnd:   "2026-10-20",
+		},
+		{
+			name:      "offset zone",
+			from:      time.Date(2026, 10, 1, 6, 0, 0, 0, ict),
+			to:        time.Date(2026, 10, 19, 7, 0, 0, 0, ict),
+			wantFirst: "2026-09-30",
+			wantEnd:   "2026-10-19",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, end := statsDays(tt.from, tt.to)
+			if first != tt.wantFirst || end != tt.wantEnd {
+				t.Errorf("statsDays = %s, %s; want %s, %s", first, end, tt.wantFirst, tt.wantEnd)
+			}
+		})
+	}
+}
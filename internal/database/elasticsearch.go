@@ -1,6 +1,8 @@
 package database
 
 import (
+	"fmt"
+
 	"blog/internal/config"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -23,23 +25,46 @@ func NewElasticsearchClient(cfg *config.ElasticsearchConfig) (*ElasticsearchClie
 
 const PostsIndex = "posts"
 
+// PostsIndexSchema is recorded in the index's _meta. Bump it whenever the
+// mapping gains fields that existing documents need to be reindexed for.
+const PostsIndexSchema = 2
+
+const postsProperties = `{
+	"id": {"type": "keyword"},
+	"title": {
+		"type": "text",
+		"analyzer": "standard"
+	},
+	"content": {
+		"type": "text",
+		"analyzer": "standard"
+	},
+	"content_format": {"type": "keyword"},
+	"excerpt": {
+		"type": "text",
+		"analyzer": "standard"
+	},
+	"word_count": {"type": "integer"},
+	"reading_time_minutes": {"type": "integer"},
+	"toc": {"type": "object", "enabled": false},
+	"tags": {"type": "keyword"},
+	"view_count": {"type": "long"},
+	"version": {"type": "long"},
+	"created_at": {"type": "date"},
+	"updated_at": {"type": "date"}
+}`
+
+// GetPostsMapping is the body that creates the posts index.
 func GetPostsMapping() string {
-	return `{
-		"mappings": {
-			"properties": {
-				"id": {"type": "keyword"},
-				"title": {
-					"type": "text",
-					"analyzer": "standard"
-				},
-				"content": {
-					"type": "text",
-					"analyzer": "standard"
-				},
-				"tags": {"type": "keyword"},
-				"created_at": {"type": "date"},
-				"updated_at": {"type": "date"}
-			}
-		}
-	}`
+	return fmt.Sprintf(`{"mappings": {"_meta": {"schema": %d}, "properties": %s}}`, PostsIndexSchema, postsProperties)
+}
+
+// GetPostsPropertiesMapping adds the current fields to an existing index.
+func GetPostsPropertiesMapping() string {
+	return fmt.Sprintf(`{"properties": %s}`, postsProperties)
+}
+
+// GetPostsSchemaMapping records PostsIndexSchema in an existing index.
+func GetPostsSchemaMapping() string {
+	return fmt.Sprintf(`{"_meta": {"schema": %d}}`, PostsIndexSchema)
 }
@@ -4,22 +4,54 @@ import (
 	"blog/internal/models"
 	"fmt"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// afterAutoMigrator is implemented by models that need schema changes
+// AutoMigrate cannot express. GORM does not call it itself.
+type afterAutoMigrator interface {
+	AfterAutoMigrate(tx *gorm.DB) error
+}
+
 func Migrate(db *gorm.DB) error {
 	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error; err != nil {
 		return fmt.Errorf("failed to create uuid-ossp extension: %w", err)
 	}
 
-	if err := db.AutoMigrate(
-		&models.Post{},        
+	tables := []interface{}{
+		&models.Post{},
 		&models.ActivityLog{},
-	); err != nil {
+		&models.PostStats{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+	}
+
+	if err := db.AutoMigrate(tables...); err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
+	for _, table := range tables {
+		if m, ok := table.(afterAutoMigrator); ok {
+			if err := m.AfterAutoMigrate(db); err != nil {
+				return fmt.Errorf("failed to run post-migration for %T: %w", table, err)
+			}
+		}
+	}
+
+	if err := backfillRenderedContent(db); err != nil {
+		return err
+	}
+
+	if err := partitionActivityLogs(db); err != nil {
+		return err
+	}
+
+	if err := EnsureActivityLogPartitions(db, time.Now().UTC()); err != nil {
+		return err
+	}
+
 	log.Println("✅ Database migration completed successfully")
 	return nil
 }
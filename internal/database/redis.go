@@ -1,8 +1,7 @@
 package database
 
 import (
-	"context"
-	"fmt"
+	"time"
 
 	"blog/internal/config"
 
@@ -13,20 +12,20 @@ type RedisClient struct {
 	*redis.Client
 }
 
-func NewRedisClient(cfg *config.RedisConfig) (*RedisClient, error) {
+// NewRedisClient does not ping the server: the cache is optional, so a
+// Redis outage at startup must not keep the API from serving.
+func NewRedisClient(cfg *config.RedisConfig) *RedisClient {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.Address(),
-		Password: cfg.Password,
-		DB:       0,
+		Addr:         cfg.Address(),
+		Password:     cfg.Password,
+		DB:           0,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+		MaxRetries:   1,
 	})
 
-	ctx := context.Background()
-	_, err := rdb.Ping(ctx).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
-	}
-
-	return &RedisClient{rdb}, nil
+	return &RedisClient{rdb}
 }
 
 func (r *RedisClient) Close() error {
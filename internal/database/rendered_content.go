@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"blog/internal/markup"
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// backfillRenderedContent renders content_html and the summary columns
+// derived from it for posts stored before those columns existed; a NULL
+// toc marks them. Version and updated_at are left alone since the post
+// itself has not changed. The search index picks the new fields up when
+// SearchService.UpgradeIndex reindexes.
+func backfillRenderedContent(db *gorm.DB) error {
+	var after uuid.UUID
+	rendered := 0
+	for {
+		var posts []models.Post
+		err := db.Select("id", "content", "content_format").
+			Where("toc IS NULL AND id > ?", after).
+			Order("id").Limit(postBatchSize).Find(&posts).Error
+		if err != nil {
+			return fmt.Errorf("failed to load posts to render: %w", err)
+		}
+
+		for _, post := range posts {
+			r, err := markup.Render(post.ContentFormat, post.Content)
+			if err != nil {
+				log.Printf("[WARN] Failed to render post %s: %v", post.ID, err)
+				continue
+			}
+			err = db.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumns(map[string]interface{}{
+				"content_html":         r.HTML,
+				"excerpt":              r.Excerpt,
+				"word_count":           r.WordCount,
+				"reading_time_minutes": r.ReadingTimeMinutes,
+				"toc":                  r.TOC,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to store rendered post %s: %w", post.ID, err)
+			}
+			rendered++
+		}
+
+		if len(posts) < postBatchSize {
+			break
+		}
+		after = posts[len(posts)-1].ID
+	}
+
+	if rendered > 0 {
+		log.Printf("Rendered content for %d existing posts", rendered)
+	}
+	return nil
+}
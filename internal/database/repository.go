@@ -1,11 +1,29 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
 	"blog/internal/models"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("duplicate key")
+	// ErrStale is returned by versioned writes when the row's version is
+	// no longer the one the caller read.
+	ErrStale = errors.New("stale version")
 )
 
+// Repositories run on the transaction bound to ctx by UnitOfWork.Do, if
+// any. Full-text search lives in services.SearchService, not here.
+
 type PostRepository struct {
 	db *gorm.DB
 }
@@ -14,54 +32,163 @@ func NewPostRepository(db *gorm.DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
-func (r *PostRepository) Create(post *models.Post) error {
-	return r.db.Create(post).Error
-}
-
-func (r *PostRepository) GetByID(id uuid.UUID) (*models.Post, error) {
+func (r *PostRepository) Get(ctx context.Context, id uuid.UUID) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("ActivityLogs").First(&post, "id = ?", id).Error
+	err := Conn(ctx, r.db).First(&post, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &post, nil
 }
 
-func (r *PostRepository) Update(post *models.Post) error {
-	return r.db.Save(post).Error
+func (r *PostRepository) ListByTag(ctx context.Context, tag string) ([]models.Post, error) {
+	var posts []models.Post
+	err := Conn(ctx, r.db).
+		Where("? = ANY(tags)", tag).
+		Order("created_at DESC").
+		Find(&posts).Error
+	return posts, err
 }
 
-func (r *PostRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Post{}, "id = ?", id).Error
-}
+func (r *PostRepository) ListRecent(ctx context.Context, tag string, limit int) ([]models.Post, error) {
+	query := Conn(ctx, r.db).Order("created_at DESC, id DESC").Limit(limit)
+	if tag != "" {
+		query = query.Where("? = ANY(tags)", tag)
+	}
 
-func (r *PostRepository) Search(req models.PostSearchRequest) ([]models.Post, int64, error) {
 	var posts []models.Post
-	var total int64
+	err := query.Find(&posts).Error
+	return posts, err
+}
+
+// postBatchSize is how many posts Each loads per query.
+const postBatchSize = 500
+
+// Each calls fn for every post, oldest first, loading them in batches.
+func (r *PostRepository) Each(ctx context.Context, fn func(post *models.Post) error) error {
+	var after *models.Post
+	for {
+		query := Conn(ctx, r.db).Order("created_at, id").Limit(postBatchSize)
+		if after != nil {
+			query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+		}
 
-	query := r.db.Model(&models.Post{})
+		var posts []models.Post
+		if err := query.Find(&posts).Error; err != nil {
+			return err
+		}
+		for i := range posts {
+			if err := fn(&posts[i]); err != nil {
+				return err
+			}
+		}
+		if len(posts) < postBatchSize {
+			return nil
+		}
+		after = &posts[len(posts)-1]
+	}
+}
 
-	if req.Query != "" {
-		query = query.Where("title ILIKE ? OR content ILIKE ?", "%"+req.Query+"%", "%"+req.Query+"%")
+func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
+	err := Conn(ctx, r.db).Create(post).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
 	}
+	return err
+}
 
-	if req.Tags != "" {
-		query = query.Where("? = ANY(tags)", req.Tags)
+// Update saves the editable columns if the stored version is still
+// post.Version, then increments post.Version. view_count is left alone:
+// only the view flusher writes it.
+func (r *PostRepository) Update(ctx context.Context, post *models.Post) error {
+	res := Conn(ctx, r.db).Model(&models.Post{}).
+		Where("id = ? AND version = ?", post.ID, post.Version).
+		Updates(map[string]interface{}{
+			"title":                post.Title,
+			"content":              post.Content,
+			"content_format":       post.ContentFormat,
+			"content_html":         post.ContentHTML,
+			"excerpt":              post.Excerpt,
+			"word_count":           post.WordCount,
+			"reading_time_minutes": post.ReadingTimeMinutes,
+			"toc":                  post.TOC,
+			"tags":                 post.Tags,
+			"updated_at":           post.UpdatedAt,
+			"version":              post.Version + 1,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStale
 	}
+	post.Version++
+	return nil
+}
 
-	query.Count(&total)
+// Delete removes the post and returns it as it was before deletion. A
+// non-zero version makes the delete conditional on it.
+func (r *PostRepository) Delete(ctx context.Context, id uuid.UUID, version int64) (*models.Post, error) {
+	q := Conn(ctx, r.db).Clauses(clause.Returning{}).Where("id = ?", id)
+	if version != 0 {
+		q = q.Where("version = ?", version)
+	}
 
-	if req.Limit > 0 {
-		query = query.Limit(req.Limit)
+	var post models.Post
+	res := q.Delete(&post)
+	if res.Error != nil {
+		return nil, res.Error
 	}
-	if req.Page > 0 {
-		query = query.Offset((req.Page - 1) * req.Limit)
+	if res.RowsAffected == 0 {
+		if version != 0 {
+			return nil, ErrStale
+		}
+		return nil, ErrNotFound
 	}
+	post.ID = id
+	return &post, nil
+}
 
-	query = query.Order("created_at DESC")
+// AddViews adds views to the post's view count and returns the post's ID,
+// title and new view count.
+func (r *PostRepository) AddViews(ctx context.Context, id uuid.UUID, views int64) (*models.Post, error) {
+	var post models.Post
+	res := Conn(ctx, r.db).Raw(
+		"UPDATE posts SET view_count = view_count + ? WHERE id = ? RETURNING id, title, view_count",
+		views, id,
+	).Scan(&post)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &post, nil
+}
 
-	err := query.Find(&posts).Error
-	return posts, total, err
+type PostStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewPostStatsRepository(db *gorm.DB) *PostStatsRepository {
+	return &PostStatsRepository{db: db}
+}
+
+// Add merges views flushed from the buffer into the post's daily stats.
+// Unique visitors are an estimate of the whole day, so the larger one wins.
+func (r *PostStatsRepository) Add(ctx context.Context, stats *models.PostStats) error {
+	return Conn(ctx, r.db).Exec(`
+		INSERT INTO post_stats (post_id, day, views, unique_visitors, updated_at)
+		VALUES (?, ?, ?, ?, NOW())
+		ON CONFLICT (post_id, day) DO UPDATE SET
+			views = post_stats.views + EXCLUDED.views,
+			unique_visitors = GREATEST(post_stats.unique_visitors, EXCLUDED.unique_visitors),
+			updated_at = NOW()`,
+		stats.PostID, stats.Day, stats.Views, stats.UniqueVisitors,
+	).Error
 }
 
 type ActivityLogRepository struct {
@@ -72,12 +199,101 @@ func NewActivityLogRepository(db *gorm.DB) *ActivityLogRepository {
 	return &ActivityLogRepository{db: db}
 }
 
-func (r *ActivityLogRepository) Create(log *models.ActivityLog) error {
-	return r.db.Create(log).Error
+func (r *ActivityLogRepository) Create(ctx context.Context, log *models.ActivityLog) error {
+	return Conn(ctx, r.db).Create(log).Error
+}
+
+// ActivityLogFilter selects activity logs. Zero fields do not filter.
+// Before is a (logged_at, id) keyset position: only older entries match.
+type ActivityLogFilter struct {
+	Action     string
+	PostID     uuid.UUID
+	Actor      string
+	From       time.Time
+	To         time.Time
+	BeforeTime time.Time
+	BeforeID   uuid.UUID
+	Limit      int
 }
 
-func (r *ActivityLogRepository) GetByPostID(postID uuid.UUID) ([]models.ActivityLog, error) {
+// List returns matching logs newest first.
+func (r *ActivityLogRepository) List(ctx context.Context, filter ActivityLogFilter) ([]models.ActivityLog, error) {
+	q := Conn(ctx, r.db).Model(&models.ActivityLog{})
+
+	if filter.Action != "" {
+		q = q.Where("action = ?", filter.Action)
+	}
+	if filter.PostID != uuid.Nil {
+		q = q.Where("post_id = ?", filter.PostID)
+	}
+	if filter.Actor != "" {
+		q = q.Where("actor = ?", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("logged_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("logged_at < ?", filter.To)
+	}
+	if !filter.BeforeTime.IsZero() {
+		q = q.Where("(logged_at, id) < (?, ?)", filter.BeforeTime, filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
 	var logs []models.ActivityLog
-	err := r.db.Where("post_id = ?", postID).Order("logged_at DESC").Find(&logs).Error
+	err := q.Order("logged_at DESC, id DESC").Find(&logs).Error
 	return logs, err
-}
\ No newline at end of file
+}
+
+// ActivityLogExpiry selects entries logged before Before, of Action if set
+// and otherwise of any action but ExceptActions.
+type ActivityLogExpiry struct {
+	Action        string
+	ExceptActions []string
+	Before        time.Time
+}
+
+// DeleteExpired deletes the matching entries, calling fn with each one, and
+// returns how many were deleted. Run it in a UnitOfWork to undo the delete
+// when fn fails.
+func (r *ActivityLogRepository) DeleteExpired(ctx context.Context, expiry ActivityLogExpiry, fn func(entry *models.ActivityLog) error) (int64, error) {
+	query := "DELETE FROM activity_logs WHERE logged_at < ?"
+	args := []interface{}{expiry.Before}
+	if expiry.Action != "" {
+		query += " AND action = ?"
+		args = append(args, expiry.Action)
+	} else if len(expiry.ExceptActions) > 0 {
+		query += " AND action NOT IN ?"
+		args = append(args, expiry.ExceptActions)
+	}
+
+	db := Conn(ctx, r.db)
+	rows, err := db.Raw(query+" RETURNING *", args...).Rows()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = eachActivityLog(db, rows, func(entry *models.ActivityLog) error {
+		n++
+		return fn(entry)
+	})
+	return n, err
+}
+
+func eachActivityLog(db *gorm.DB, rows *sql.Rows, fn func(entry *models.ActivityLog) error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.ActivityLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
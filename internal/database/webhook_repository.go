@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return Conn(ctx, r.db).Create(webhook).Error
+}
+
+// List returns all webhooks, newest first.
+func (r *WebhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := Conn(ctx, r.db).Order("created_at DESC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListSubscribed returns the active webhooks subscribed to event.
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, event string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := Conn(ctx, r.db).Where("active AND ? = ANY(events)", event).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepository) GetMany(ctx context.Context, ids []uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := Conn(ctx, r.db).Where("id IN ?", ids).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	err := Conn(ctx, r.db).Model(&models.Webhook{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// Delete removes the webhook and, by cascade, its deliveries.
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	res := Conn(ctx, r.db).Delete(&models.Webhook{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns the webhook's deliveries newest first, only those
+// in status unless it is empty.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	q := Conn(ctx, r.db).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := q.Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return Conn(ctx, r.db).Create(&deliveries).Error
+}
+
+// ClaimDueDeliveries locks up to limit pending deliveries that are due and
+// moves their next attempt to leaseEnd, so no other worker claims them
+// before then. Rows locked by a concurrent claim are skipped.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, leaseEnd time.Time) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	err := Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(due))
+		for i, d := range due {
+			ids[i] = d.ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", leaseEnd).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	return Conn(ctx, r.db).Model(&models.WebhookDelivery{ID: id}).Updates(updates).Error
+}
@@ -0,0 +1,249 @@
+// Package feed renders lists of posts as RSS 2.0, Atom and JSON Feed 1.1
+// documents.
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	JSONContentType = "application/feed+json; charset=utf-8"
+)
+
+// Feed is a format-independent syndication feed.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed describes and FeedURL the feed itself.
+	Link    string
+	FeedURL string
+	// Updated is the latest change of any item.
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	// ID is a permanent, globally unique identifier, e.g. "urn:uuid:...".
+	ID    string
+	URL   string
+	Title string
+	// Content is the source text; ContentHTML, when set, is the same content
+	// as sanitized HTML and is preferred by formats that carry markup.
+	Content     string
+	ContentHTML string
+	Summary     string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders f as RSS 2.0.
+func RSS(f *Feed) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+			Self:        atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, len(f.Items)),
+		},
+	}
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for i, item := range f.Items {
+		doc.Channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: item.content(),
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags,
+		}
+	}
+	return marshalXML(doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       atomLink       `xml:"link"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomText       `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom renders f as an Atom (RFC 4287) feed identified by its FeedURL.
+func Atom(f *Feed) ([]byte, error) {
+	updated := f.Updated
+	if updated.IsZero() {
+		// updated is required even when the feed is empty.
+		updated = time.Unix(0, 0)
+	}
+	doc := atomFeed{
+		ID:      f.FeedURL,
+		Title:   f.Title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: f.Title},
+		Links: []atomLink{
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate"},
+		},
+		Entries: make([]atomEntry, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: item.URL, Rel: "alternate"},
+			Summary:   item.Summary,
+			Content:   atomText{Type: "text", Value: item.Content},
+		}
+		if item.ContentHTML != "" {
+			entry.Content = atomText{Type: "html", Value: item.ContentHTML}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries[i] = entry
+	}
+	return marshalXML(doc)
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url,omitempty"`
+	FeedURL     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	ContentText   string   `json:"content_text"`
+	Summary       string   `json:"summary,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+}
+
+// JSON renders f as JSON Feed 1.1.
+func JSON(f *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       make([]jsonItem, len(f.Items)),
+	}
+	for i, item := range f.Items {
+		doc.Items[i] = jsonItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			ContentText:   item.Content,
+			Summary:       item.Summary,
+			Tags:          item.Tags,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON feed: %w", err)
+	}
+	return data, nil
+}
+
+func (i *Item) content() string {
+	if i.ContentHTML != "" {
+		return i.ContentHTML
+	}
+	return i.Content
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode feed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
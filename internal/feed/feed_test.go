@@ -0,0 +1,96 @@
+package feed_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/internal/feed"
+)
+
+var testFeed = &feed.Feed{
+	Title:   "Blog",
+	Link:    "https://blog.example/",
+	FeedURL: "https://blog.example/feed.atom",
+	Updated: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+	Items: []feed.Item{{
+		ID:        "urn:uuid:2b1f0c8e-7d1a-4b4e-9a53-0f5d3f7c9a10",
+		URL:       "https://blog.example/posts/hello",
+		Title:     "Hello <world>",
+		Content:   "a & b",
+		Tags:      []string{"go"},
+		Published: time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("ICT", 7*3600)),
+		Updated:   time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+	}},
+}
+
+func TestRSS(t *testing.T) {
+	data, err := feed.RSS(testFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Channel struct {
+			Items []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, data)
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != "Hello <world>" || item.GUID != testFeed.Items[0].ID {
+		t.Errorf("item = %+v", item)
+	}
+	if item.PubDate != "Wed, 01 May 2024 01:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+	if !strings.Contains(string(data), `<atom:link href="https://blog.example/feed.atom" rel="self"`) {
+		t.Errorf("no self link:\n%s", data)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	data, err := feed.Atom(testFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		XMLName xml.Name
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, data)
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2005/Atom" || doc.ID != testFeed.FeedURL {
+		t.Errorf("feed = %+v", doc)
+	}
+	if doc.Updated != "2024-05-02T10:00:00Z" || doc.Entries[0].Content != "a & b" {
+		t.Errorf("feed = %+v", doc)
+	}
+}
+
+func TestEmptyFeed(t *testing.T) {
+	empty := &feed.Feed{Title: "Blog", FeedURL: "https://blog.example/feed.json"}
+	for name, render := range map[string]func(*feed.Feed) ([]byte, error){
+		"rss": feed.RSS, "atom": feed.Atom, "json": feed.JSON,
+	} {
+		data, err := render(empty)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if name == "json" && !strings.Contains(string(data), `"items":[]`) {
+			t.Errorf("json: %s", data)
+		}
+	}
+}
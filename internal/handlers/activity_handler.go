@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog/internal/models"
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ActivityHandler struct {
+	activityService *services.ActivityService
+}
+
+func NewActivityHandler(activityService *services.ActivityService) *ActivityHandler {
+	return &ActivityHandler{activityService: activityService}
+}
+
+func (h *ActivityHandler) ListActivity(c *gin.Context) {
+	var query models.ActivityLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+
+	h.list(c, &query)
+}
+
+func (h *ActivityHandler) ListPostActivity(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	var query models.ActivityLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	query.PostID = id.String()
+
+	h.list(c, &query)
+}
+
+func (h *ActivityHandler) list(c *gin.Context, query *models.ActivityLogQuery) {
+	result, err := h.activityService.ListActivity(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to list activity")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Activity retrieved successfully", result)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"blog/internal/models"
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+func (h *AnalyticsHandler) DailyPostActivity(c *gin.Context) {
+	handleAnalytics(c, h.analyticsService.DailyPostActivity)
+}
+
+func (h *AnalyticsHandler) MostEditedPosts(c *gin.Context) {
+	handleAnalytics(c, h.analyticsService.MostEditedPosts)
+}
+
+func (h *AnalyticsHandler) MostViewedPosts(c *gin.Context) {
+	handleAnalytics(c, h.analyticsService.MostViewedPosts)
+}
+
+func (h *AnalyticsHandler) TagTrends(c *gin.Context) {
+	handleAnalytics(c, h.analyticsService.TagTrends)
+}
+
+func handleAnalytics[T any](c *gin.Context, compute func(context.Context, *models.AnalyticsQuery) ([]T, error)) {
+	var query models.AnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+
+	result, err := compute(c.Request.Context(), &query)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to compute analytics")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Analytics retrieved successfully", result)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/services"
+)
+
+// postETag is a strong entity tag for the post as rendered:
+// "<version>.<view_count>", with a ".html" suffix when the body carries
+// content_html. The version leads so If-Match can compare on it alone.
+func postETag(post *models.Post, withHTML bool) string {
+	tag := strconv.FormatInt(post.Version, 10) + "." + strconv.FormatInt(post.ViewCount, 10)
+	if withHTML {
+		tag += ".html"
+	}
+	return `"` + tag + `"`
+}
+
+// parseIfMatch turns an If-Match header into a Precondition. If-Match uses
+// strong comparison, so weak and foreign entity tags never match. Only the
+// version part of a tag is compared: views and the rendered variant do not
+// make a write stale.
+func parseIfMatch(header string) services.Precondition {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return services.Precondition{}
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], ".")
+		if version, err := strconv.ParseInt(version, 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return services.Precondition{Versions: versions}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	eventHeartbeatInterval = 15 * time.Second
+	eventRetryMillis       = 3000
+
+	// eventResync tells the client that events may have been missed and it
+	// should reload its state instead of relying on the stream.
+	eventResync = "resync"
+)
+
+type EventHandler struct {
+	eventStream *services.EventStreamService
+}
+
+func NewEventHandler(eventStream *services.EventStreamService) *EventHandler {
+	return &EventHandler{eventStream: eventStream}
+}
+
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	if lastID != "" && !services.ValidEventID(lastID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid Last-Event-ID", nil)
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is lost;
+	// live events already covered by the replay are skipped below.
+	events, unsubscribe := h.eventStream.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Render(-1, sse.Event{Retry: eventRetryMillis, Data: "connected"})
+
+	if lastID != "" {
+		missed, complete, err := h.eventStream.Replay(c.Request.Context(), lastID)
+		if err != nil || !complete {
+			c.Render(-1, sse.Event{Event: eventResync, Data: "events may have been missed"})
+		}
+		for _, event := range missed {
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			lastID = event.ID
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if lastID != "" && !services.EventIDAfter(event.ID, lastID) {
+				return true
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+			lastID = event.ID
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/feed"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// surrogateKeyFeeds tags every feed response.
+const surrogateKeyFeeds = "feeds"
+
+type FeedHandler struct {
+	feedService *services.FeedService
+	site        *config.SiteConfig
+	cfg         *config.PostsConfig
+}
+
+func NewFeedHandler(feedService *services.FeedService, site *config.SiteConfig, cfg *config.PostsConfig) *FeedHandler {
+	return &FeedHandler{feedService: feedService, site: site, cfg: cfg}
+}
+
+func (h *FeedHandler) RSS(c *gin.Context) {
+	h.serve(c, feed.RSS, feed.RSSContentType)
+}
+
+func (h *FeedHandler) Atom(c *gin.Context) {
+	h.serve(c, feed.Atom, feed.AtomContentType)
+}
+
+func (h *FeedHandler) JSON(c *gin.Context) {
+	h.serve(c, feed.JSON, feed.JSONContentType)
+}
+
+// serve renders the feed of the newest posts, limited to the :tag path
+// parameter when the route has one.
+func (h *FeedHandler) serve(c *gin.Context, render func(*feed.Feed) ([]byte, error), contentType string) {
+	tag := c.Param("tag")
+
+	posts, changed, err := h.feedService.RecentPosts(c.Request.Context(), tag)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to load feed")
+		return
+	}
+
+	f := h.buildFeed(c.Request.URL.Path, tag, posts)
+	body, err := render(f)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to render feed")
+		return
+	}
+
+	etag := bodyETag(body)
+	keys := []string{surrogateKeyPosts, surrogateKeyFeeds}
+	for _, post := range posts {
+		keys = append(keys, postSurrogateKey(post.ID.String()))
+	}
+	setCacheHeaders(c, h.site.FeedCacheControl, h.cfg.SurrogateKeyHeader, etag, changed, keys...)
+	if notModified(c.Request, etag, changed) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+func (h *FeedHandler) buildFeed(path, tag string, posts []models.Post) *feed.Feed {
+	baseURL := strings.TrimSuffix(h.site.BaseURL, "/")
+	f := &feed.Feed{
+		Title:       h.site.Title,
+		Description: h.site.Description,
+		Link:        baseURL + "/",
+		FeedURL:     baseURL + path,
+		Items:       make([]feed.Item, len(posts)),
+	}
+	if tag != "" {
+		f.Title = fmt.Sprintf("%s: %s", h.site.Title, tag)
+	}
+
+	var updated time.Time
+	for i, post := range posts {
+		if post.UpdatedAt.After(updated) {
+			updated = post.UpdatedAt
+		}
+		f.Items[i] = feed.Item{
+			ID:          "urn:uuid:" + post.ID.String(),
+			URL:         h.site.PostURL(post.ID.String(), post.Slug()),
+			Title:       post.Title,
+			Content:     post.Content,
+			ContentHTML: post.ContentHTML,
+			Summary:     post.Excerpt,
+			Tags:        post.Tags,
+			Published:   post.CreatedAt,
+			Updated:     post.UpdatedAt,
+		}
+	}
+	f.Updated = updated
+	return f
+}
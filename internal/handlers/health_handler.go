@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	cacheService *services.CacheService
+}
+
+func NewHealthHandler(cacheService *services.CacheService) *HealthHandler {
+	return &HealthHandler{cacheService: cacheService}
+}
+
+// Health stays 200 when Redis is down: the API keeps serving from
+// PostgreSQL, so the instance is degraded rather than unhealthy.
+func (h *HealthHandler) Health(c *gin.Context) {
+	status := "ok"
+	redisStatus := "ok"
+	if !h.cacheService.Available() {
+		status = "degraded"
+		redisStatus = "unavailable"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"redis":  redisStatus,
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"blog/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// surrogateKeyPosts tags every cached post and search response, so a
+// reverse proxy can purge them all at once, e.g. after a post is created.
+const surrogateKeyPosts = "posts"
+
+func postSurrogateKey(id string) string {
+	return "post-" + id
+}
+
+// searchETag is a strong entity tag over the search result, which includes
+// view counts and so changes more often than any single post's version.
+func searchETag(result *models.PostSearchResponse) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ""
+	}
+	return bodyETag(data)
+}
+
+// bodyETag is a strong entity tag over a rendered response body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%x"`, sum[:16])
+}
+
+// setCacheHeaders sets the validators and caching directives of a GET
+// response. A zero lastModified sends no Last-Modified header.
+func setCacheHeaders(c *gin.Context, cacheControl, surrogateKeyHeader, etag string, lastModified time.Time, keys ...string) {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+	if surrogateKeyHeader != "" && len(keys) > 0 {
+		c.Header(surrogateKeyHeader, strings.Join(keys, " "))
+	}
+}
+
+// notModified reports whether the client's cached copy is still current.
+// If-None-Match takes precedence over If-Modified-Since and uses weak
+// comparison.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Last-Modified only has second precision.
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"blog/internal/middleware"
+	"blog/internal/models"
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkLineSize bounds a single NDJSON line, i.e. one post.
+const maxBulkLineSize = 10 << 20
+
+// BulkPosts runs an NDJSON batch of create, update and delete operations
+// and reports a status per line. The batch runs in one transaction unless
+// atomic=false is given, in which case each operation commits on its own.
+func (h *PostHandler) BulkPosts(c *gin.Context) {
+	atomic, err := strconv.ParseBool(c.DefaultQuery("atomic", "true"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid atomic parameter", err)
+		return
+	}
+
+	ops, err := readBulkOperations(c.Request.Body, h.cfg.BulkMaxOperations)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid bulk request", err)
+		return
+	}
+	if len(ops) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Bulk request has no operations", nil)
+		return
+	}
+
+	results := h.postService.Bulk(c.Request.Context(), ops, atomic)
+	utils.SuccessResponse(c, http.StatusOK, "Bulk request processed", bulkResponse(ops, results, atomic))
+}
+
+func bulkResponse(ops []models.BulkOperation, results []services.BulkResult, atomic bool) models.BulkResponse {
+	response := models.BulkResponse{Atomic: atomic, Items: make([]models.BulkItemResult, len(results))}
+	for i, result := range results {
+		item := models.BulkItemResult{Op: ops[i].Op, ID: ops[i].ID, Status: http.StatusOK}
+		switch {
+		case result.Err != nil:
+			item.Status = middleware.StatusForError(result.Err)
+			item.Code = utils.ErrorCode(item.Status, result.Err)
+			item.Error = result.Err.Error()
+			if item.Status >= http.StatusInternalServerError {
+				log.Printf("[ERROR] bulk operation %d (%s %s): %v", i, item.Op, item.ID, result.Err)
+				item.Error = http.StatusText(item.Status)
+			}
+			response.Errors = true
+		case result.Post != nil:
+			item.ID = result.Post.ID.String()
+			item.Version = result.Post.Version
+			if item.Op == models.BulkCreate {
+				item.Status = http.StatusCreated
+			}
+		}
+		response.Items[i] = item
+	}
+	return response
+}
+
+// readBulkOperations decodes one operation per non-empty line.
+func readBulkOperations(r io.Reader, max int) ([]models.BulkOperation, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxBulkLineSize)
+
+	var ops []models.BulkOperation
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if max > 0 && len(ops) == max {
+			return nil, fmt.Errorf("more than %d operations", max)
+		}
+
+		var op models.BulkOperation
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&op); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 
+	"blog/internal/config"
 	"blog/internal/models"
+	"blog/internal/patch"
 	"blog/internal/services"
 	"blog/internal/utils"
 
@@ -13,10 +19,16 @@ import (
 
 type PostHandler struct {
 	postService *services.PostService
+	viewService *services.ViewService
+	cfg         *config.PostsConfig
 }
 
-func NewPostHandler(postService *services.PostService) *PostHandler {
-	return &PostHandler{postService: postService}
+func NewPostHandler(postService *services.PostService, viewService *services.ViewService, cfg *config.PostsConfig) *PostHandler {
+	return &PostHandler{
+		postService: postService,
+		viewService: viewService,
+		cfg:         cfg,
+	}
 }
 
 func (h *PostHandler) CreatePost(c *gin.Context) {
@@ -28,11 +40,12 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	post, err := h.postService.CreatePost(c.Request.Context(), &req)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create post", err)
+		c.Error(err).SetMeta("Failed to create post")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusCreated, "Post created successfully", post.ToResponse())
+	c.Header("ETag", postETag(post, includeContentHTML(c)))
+	utils.SuccessResponse(c, http.StatusCreated, "Post created successfully", postResponse(c, post))
 }
 
 func (h *PostHandler) GetPost(c *gin.Context) {
@@ -45,15 +58,24 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 
 	post, err := h.postService.GetPost(c.Request.Context(), id)
 	if err != nil {
-		if err.Error() == "post not found" {
-			utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err)
-			return
-		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get post", err)
+		c.Error(err).SetMeta("Failed to get post")
+		return
+	}
+
+	visitor := c.ClientIP() + "|" + c.Request.UserAgent()
+	if err := h.viewService.RecordView(c.Request.Context(), id, visitor); err != nil && !errors.Is(err, services.ErrCacheUnavailable) {
+		log.Printf("[WARN] Failed to record post view: %v", err)
+	}
+
+	etag := postETag(post, includeContentHTML(c))
+	setCacheHeaders(c, h.cfg.CacheControl, h.cfg.SurrogateKeyHeader, etag, post.UpdatedAt,
+		surrogateKeyPosts, postSurrogateKey(post.ID.String()))
+	if notModified(c.Request, etag, post.UpdatedAt) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Post retrieved successfully", post.ToResponse())
+	utils.SuccessResponse(c, http.StatusOK, "Post retrieved successfully", postResponse(c, post))
 }
 
 func (h *PostHandler) UpdatePost(c *gin.Context) {
@@ -70,17 +92,68 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.UpdatePost(c.Request.Context(), id, &req)
+	pre, err := h.precondition(c)
 	if err != nil {
-		if err.Error() == "post not found" {
-			utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err)
-			return
-		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update post", err)
+		c.Error(err).SetMeta("Failed to update post")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Post updated successfully", post.ToResponse())
+	post, err := h.postService.UpdatePost(c.Request.Context(), id, &req, pre)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to update post")
+		return
+	}
+
+	c.Header("ETag", postETag(post, includeContentHTML(c)))
+	utils.SuccessResponse(c, http.StatusOK, "Post updated successfully", postResponse(c, post))
+}
+
+// PatchPost accepts application/merge-patch+json and
+// application/json-patch+json bodies.
+func (h *PostHandler) PatchPost(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid post ID", err)
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var p patch.Patch
+	switch c.ContentType() {
+	case patch.MergePatchContentType:
+		p, err = patch.DecodeMergePatch(body)
+	case patch.JSONPatchContentType:
+		p, err = patch.DecodeJSONPatch(body)
+	default:
+		c.Header("Accept-Patch", patch.MergePatchContentType+", "+patch.JSONPatchContentType)
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported patch format", nil)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid patch document", err)
+		return
+	}
+
+	pre, err := h.precondition(c)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to patch post")
+		return
+	}
+
+	post, err := h.postService.PatchPost(c.Request.Context(), id, p, pre)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to patch post")
+		return
+	}
+
+	c.Header("ETag", postETag(post, includeContentHTML(c)))
+	utils.SuccessResponse(c, http.StatusOK, "Post updated successfully", postResponse(c, post))
 }
 
 func (h *PostHandler) DeletePost(c *gin.Context) {
@@ -91,13 +164,15 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	err = h.postService.DeletePost(c.Request.Context(), id)
+	pre, err := h.precondition(c)
 	if err != nil {
-		if err.Error() == "post not found" {
-			utils.ErrorResponse(c, http.StatusNotFound, "Post not found", err)
-			return
-		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete post", err)
+		c.Error(err).SetMeta("Failed to delete post")
+		return
+	}
+
+	err = h.postService.DeletePost(c.Request.Context(), id, pre)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to delete post")
 		return
 	}
 
@@ -113,14 +188,43 @@ func (h *PostHandler) SearchPostsByTag(c *gin.Context) {
 
 	posts, err := h.postService.SearchByTag(c.Request.Context(), tag)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search posts", err)
+		c.Error(err).SetMeta("Failed to search posts")
 		return
 	}
 
 	responses := make([]models.PostResponse, len(posts))
-	for i, post := range posts {
-		responses[i] = post.ToResponse()
+	for i := range posts {
+		responses[i] = postResponse(c, &posts[i])
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Posts retrieved successfully", responses)
 }
+
+// precondition reads If-Match for a write, which must be present when the
+// server requires conditional writes.
+func (h *PostHandler) precondition(c *gin.Context) (services.Precondition, error) {
+	header := c.GetHeader("If-Match")
+	if header == "" && h.cfg.RequireIfMatch {
+		return services.Precondition{}, services.ErrPreconditionRequired
+	}
+	return parseIfMatch(header), nil
+}
+
+// postResponse adds the rendered HTML when the client asks for it with
+// ?include=content_html.
+func postResponse(c *gin.Context, post *models.Post) models.PostResponse {
+	resp := post.ToResponse()
+	if includeContentHTML(c) {
+		resp.ContentHTML = post.ContentHTML
+	}
+	return resp
+}
+
+func includeContentHTML(c *gin.Context) bool {
+	for _, field := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(field) == "content_html" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,833 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/handlers"
+	"blog/internal/memory"
+	"blog/internal/middleware"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type testServer struct {
+	router *gin.Engine
+	posts  *memory.PostRepository
+	cache  *memory.Cache
+	index  *memory.SearchIndex
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithConfig(t, &config.PostsConfig{
+		CacheControl:       "public, max-age=60",
+		SearchCacheControl: "public, max-age=30",
+		SurrogateKeyHeader: "Surrogate-Key",
+	})
+}
+
+func newTestServerWithConfig(t *testing.T, cfg *config.PostsConfig) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	posts := memory.NewPostRepository()
+	cache := memory.NewCache()
+	index := memory.NewSearchIndex()
+
+	events := services.NewEventBus()
+	events.Subscribe(services.InvalidateCachedPosts(cache))
+	events.SubscribeBatch(services.SyncSearchIndex(index))
+	feedService := services.NewFeedService(posts, cache, 20)
+	events.Subscribe(feedService.HandlePostEvent)
+
+	uow := memory.NewUnitOfWork(posts)
+	postHandler := handlers.NewPostHandler(
+		services.NewPostService(uow, posts, cache, events),
+		services.NewViewService(uow, posts, nil, cache, index, nil, true),
+		cfg,
+	)
+	searchHandler := handlers.NewSearchHandler(index, cfg)
+	site := &config.SiteConfig{
+		BaseURL:             "https://blog.example",
+		Title:               "Blog",
+		PostPath:            "/posts/{slug}",
+		FeedCacheControl:    "public, max-age=300",
+		SitemapCacheControl: "public, max-age=3600",
+	}
+	feedHandler := handlers.NewFeedHandler(feedService, site, cfg)
+	sitemapHandler := handlers.NewSitemapHandler(services.NewSitemapService(posts, site), site)
+
+	router := gin.New()
+	router.Use(middleware.ErrorMiddleware())
+	api := router.Group("/api/v1")
+	api.POST("/posts", postHandler.CreatePost)
+	api.POST("/posts/_bulk", postHandler.BulkPosts)
+	api.GET("/posts/export", postHandler.ExportPosts)
+	api.POST("/posts/import", postHandler.ImportPosts)
+	api.GET("/posts/:id", postHandler.GetPost)
+	api.PUT("/posts/:id", postHandler.UpdatePost)
+	api.PATCH("/posts/:id", postHandler.PatchPost)
+	api.DELETE("/posts/:id", postHandler.DeletePost)
+	api.GET("/posts/search", searchHandler.SearchPosts)
+	api.GET("/posts/search-by-tag", postHandler.SearchPostsByTag)
+	router.GET("/feed.rss", feedHandler.RSS)
+	router.GET("/feed.atom", feedHandler.Atom)
+	router.GET("/feed.json", feedHandler.JSON)
+	router.GET("/tags/:tag/feed.atom", feedHandler.Atom)
+	router.GET("/sitemap.xml", sitemapHandler.Index)
+	router.GET("/sitemaps/:file", sitemapHandler.Chunk)
+
+	return &testServer{router: router, posts: posts, cache: cache, index: index}
+}
+
+// apiResponse decodes both success bodies and problem+json errors.
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Code    string          `json:"code"`
+	Detail  string          `json:"detail"`
+}
+
+func (s *testServer) do(t *testing.T, method, path string, body interface{}) (int, apiResponse) {
+	t.Helper()
+	rec := s.doWithHeaders(t, method, path, body, nil)
+
+	var res apiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s %s: invalid response body %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, res
+}
+
+func (s *testServer) doWithHeaders(t *testing.T, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *testServer) createPost(t *testing.T, req models.PostCreateRequest) models.PostResponse {
+	t.Helper()
+
+	code, res := s.do(t, http.MethodPost, "/api/v1/posts", req)
+	if code != http.StatusCreated {
+		t.Fatalf("create post: status %d, error %q", code, res.Detail)
+	}
+	var post models.PostResponse
+	if err := json.Unmarshal(res.Data, &post); err != nil {
+		t.Fatalf("decode post: %v", err)
+	}
+	return post
+}
+
+func TestPostCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World", Tags: []string{"go"}})
+	path := "/api/v1/posts/" + created.ID
+
+	code, res := s.do(t, http.MethodGet, path, nil)
+	if code != http.StatusOK {
+		t.Fatalf("get: status %d, error %q", code, res.Detail)
+	}
+	var got models.PostResponse
+	json.Unmarshal(res.Data, &got)
+	if got.Title != "Hello" || got.Content != "World" {
+		t.Errorf("get returned %+v", got)
+	}
+	if views := s.cache.PendingViews(uuid.MustParse(created.ID)); views != 1 {
+		t.Errorf("recorded %d views, want 1", views)
+	}
+
+	code, res = s.do(t, http.MethodPut, path, map[string]interface{}{"title": "Updated"})
+	if code != http.StatusOK {
+		t.Fatalf("update: status %d, error %q", code, res.Detail)
+	}
+	json.Unmarshal(res.Data, &got)
+	if got.Title != "Updated" || got.Content != "World" {
+		t.Errorf("update returned %+v", got)
+	}
+
+	code, res = s.do(t, http.MethodGet, path, nil)
+	json.Unmarshal(res.Data, &got)
+	if code != http.StatusOK || got.Title != "Updated" {
+		t.Errorf("get after update: status %d, title %q", code, got.Title)
+	}
+
+	if code, res = s.do(t, http.MethodDelete, path, nil); code != http.StatusOK {
+		t.Fatalf("delete: status %d, error %q", code, res.Detail)
+	}
+	if code, _ = s.do(t, http.MethodGet, path, nil); code != http.StatusNotFound {
+		t.Errorf("get after delete: status %d, want 404", code)
+	}
+}
+
+func TestCreatePostValidation(t *testing.T) {
+	s := newTestServer(t)
+
+	code, res := s.do(t, http.MethodPost, "/api/v1/posts", map[string]string{"title": "No content"})
+	if code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", code)
+	}
+	if res.Code != "validation_failed" {
+		t.Errorf("code = %q, want validation_failed", res.Code)
+	}
+}
+
+func TestPostNotFound(t *testing.T) {
+	s := newTestServer(t)
+	path := "/api/v1/posts/" + uuid.NewString()
+
+	if code, res := s.do(t, http.MethodGet, path, nil); code != http.StatusNotFound || res.Code != "post_not_found" {
+		t.Errorf("get: status %d code %q, want 404 post_not_found", code, res.Code)
+	}
+	if code, _ := s.do(t, http.MethodPut, path, map[string]string{"title": "x"}); code != http.StatusNotFound {
+		t.Errorf("update: status %d, want 404", code)
+	}
+	if code, _ := s.do(t, http.MethodDelete, path, nil); code != http.StatusNotFound {
+		t.Errorf("delete: status %d, want 404", code)
+	}
+}
+
+func TestInvalidPostID(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if code, _ := s.do(t, method, "/api/v1/posts/not-a-uuid", map[string]string{}); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", method, code)
+		}
+	}
+}
+
+func TestGetPostWithCacheDown(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	s.cache.Unavailable = true
+
+	if code, res := s.do(t, http.MethodGet, "/api/v1/posts/"+created.ID, nil); code != http.StatusOK {
+		t.Errorf("status %d, error %q; want 200 while the cache is down", code, res.Detail)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	s := newTestServer(t)
+	s.createPost(t, models.PostCreateRequest{Title: "Learning Go", Content: "Goroutines", Tags: []string{"go"}})
+	s.createPost(t, models.PostCreateRequest{Title: "Learning Rust", Content: "Ownership", Tags: []string{"rust"}})
+
+	code, res := s.do(t, http.MethodGet, "/api/v1/posts/search?q=learning&tags=rust", nil)
+	if code != http.StatusOK {
+		t.Fatalf("search: status %d, error %q", code, res.Detail)
+	}
+	var result models.PostSearchResponse
+	json.Unmarshal(res.Data, &result)
+	if result.TotalCount != 1 || result.Posts[0].Title != "Learning Rust" {
+		t.Errorf("search returned %+v", result)
+	}
+
+	if code, _ := s.do(t, http.MethodGet, "/api/v1/posts/search?sort=oldest", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid sort: status %d, want 400", code)
+	}
+}
+
+func TestSearchPostsByTag(t *testing.T) {
+	s := newTestServer(t)
+	s.createPost(t, models.PostCreateRequest{Title: "One", Content: "x", Tags: []string{"go"}})
+	s.createPost(t, models.PostCreateRequest{Title: "Two", Content: "x", Tags: []string{"rust"}})
+
+	code, res := s.do(t, http.MethodGet, "/api/v1/posts/search-by-tag?tag=go", nil)
+	if code != http.StatusOK {
+		t.Fatalf("status %d, error %q", code, res.Detail)
+	}
+	var posts []models.PostResponse
+	json.Unmarshal(res.Data, &posts)
+	if len(posts) != 1 || posts[0].Title != "One" {
+		t.Errorf("search-by-tag returned %+v", posts)
+	}
+
+	if code, _ := s.do(t, http.MethodGet, "/api/v1/posts/search-by-tag", nil); code != http.StatusBadRequest {
+		t.Errorf("missing tag: status %d, want 400", code)
+	}
+}
+
+func TestUpdatePostIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	path := "/api/v1/posts/" + created.ID
+
+	get := s.doWithHeaders(t, http.MethodGet, path, nil, nil)
+	etag := get.Header().Get("ETag")
+	if etag != `"1.0"` {
+		t.Fatalf("ETag = %q, want \"1.0\"", etag)
+	}
+
+	update := s.doWithHeaders(t, http.MethodPut, path, map[string]string{"title": "First"}, map[string]string{"If-Match": etag})
+	if update.Code != http.StatusOK {
+		t.Fatalf("update with current ETag: status %d", update.Code)
+	}
+	if got := update.Header().Get("ETag"); got != `"2.0"` {
+		t.Errorf("ETag after update = %q, want \"2.0\"", got)
+	}
+
+	stale := s.doWithHeaders(t, http.MethodPut, path, map[string]string{"title": "Second"}, map[string]string{"If-Match": etag})
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("update with stale ETag: status %d, want 412", stale.Code)
+	}
+
+	weak := s.doWithHeaders(t, http.MethodDelete, path, nil, map[string]string{"If-Match": `W/"2"`})
+	if weak.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete with weak ETag: status %d, want 412", weak.Code)
+	}
+
+	if rec := s.doWithHeaders(t, http.MethodDelete, path, nil, map[string]string{"If-Match": `"7", "2"`}); rec.Code != http.StatusOK {
+		t.Errorf("delete with matching ETag in list: status %d, want 200", rec.Code)
+	}
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	s := newTestServerWithConfig(t, &config.PostsConfig{RequireIfMatch: true})
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	path := "/api/v1/posts/" + created.ID
+
+	if code, res := s.do(t, http.MethodPut, path, map[string]string{"title": "x"}); code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match: status %d (%s), want 428", code, res.Code)
+	}
+	if code, _ := s.do(t, http.MethodDelete, path, nil); code != http.StatusPreconditionRequired {
+		t.Errorf("delete without If-Match: status %d, want 428", code)
+	}
+	if rec := s.doWithHeaders(t, http.MethodDelete, path, nil, map[string]string{"If-Match": "*"}); rec.Code != http.StatusOK {
+		t.Errorf("delete with If-Match *: status %d, want 200", rec.Code)
+	}
+}
+
+func TestGetPostConditional(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	path := "/api/v1/posts/" + created.ID
+
+	rec := s.doWithHeaders(t, http.MethodGet, path, nil, nil)
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got, want := rec.Header().Get("Surrogate-Key"), "posts post-"+created.ID; got != want {
+		t.Errorf("Surrogate-Key = %q, want %q", got, want)
+	}
+	etag := rec.Header().Get("ETag")
+	lastModified := rec.Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("no Last-Modified header")
+	}
+
+	for name, headers := range map[string]map[string]string{
+		"If-None-Match":      {"If-None-Match": etag},
+		"weak If-None-Match": {"If-None-Match": `"9", W/` + etag},
+		"If-Modified-Since":  {"If-Modified-Since": lastModified},
+	} {
+		rec := s.doWithHeaders(t, http.MethodGet, path, nil, headers)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: status %d, want 304", name, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s: 304 with body %q", name, rec.Body.String())
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("%s: 304 without ETag", name)
+		}
+	}
+
+	// If-None-Match wins over a matching If-Modified-Since.
+	rec = s.doWithHeaders(t, http.MethodGet, path, nil, map[string]string{
+		"If-None-Match":     `"9"`,
+		"If-Modified-Since": lastModified,
+	})
+	if rec.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: status %d, want 200", rec.Code)
+	}
+
+	s.do(t, http.MethodPut, path, map[string]string{"title": "Updated"})
+	if rec := s.doWithHeaders(t, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Errorf("after update: status %d, want 200", rec.Code)
+	}
+}
+
+func TestGetPostETagTracksRepresentation(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	path := "/api/v1/posts/" + created.ID
+
+	etag := s.doWithHeaders(t, http.MethodGet, path, nil, nil).Header().Get("ETag")
+	html := s.doWithHeaders(t, http.MethodGet, path+"?include=content_html", nil, nil).Header().Get("ETag")
+	if html == etag {
+		t.Errorf("include=content_html shares ETag %s with the plain body", etag)
+	}
+	if rec := s.doWithHeaders(t, http.MethodGet, path+"?include=content_html", nil, map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Errorf("plain ETag on include=content_html: status %d, want 200", rec.Code)
+	}
+
+	id := uuid.MustParse(created.ID)
+	if _, err := s.posts.AddViews(context.Background(), id, 5); err != nil {
+		t.Fatalf("AddViews: %v", err)
+	}
+	s.cache.DeletePost(context.Background(), id)
+
+	rec := s.doWithHeaders(t, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK {
+		t.Errorf("after views: status %d, want 200", rec.Code)
+	}
+
+	// Views alone do not make a write stale.
+	update := s.doWithHeaders(t, http.MethodPut, path, map[string]string{"title": "Views"}, map[string]string{"If-Match": etag})
+	if update.Code != http.StatusOK {
+		t.Errorf("update with pre-view ETag: status %d, want 200", update.Code)
+	}
+}
+
+func TestSearchPostsConditional(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Learning Go", Content: "Goroutines"})
+	path := "/api/v1/posts/search?q=learning"
+
+	rec := s.doWithHeaders(t, http.MethodGet, path, nil, nil)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag header")
+	}
+	if got, want := rec.Header().Get("Surrogate-Key"), "posts post-"+created.ID; got != want {
+		t.Errorf("Surrogate-Key = %q, want %q", got, want)
+	}
+
+	if rec := s.doWithHeaders(t, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged results: status %d, want 304", rec.Code)
+	}
+
+	s.createPost(t, models.PostCreateRequest{Title: "Learning Rust", Content: "Ownership"})
+	if rec := s.doWithHeaders(t, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Errorf("changed results: status %d, want 200", rec.Code)
+	}
+}
+
+func (s *testServer) patch(t *testing.T, path, contentType, body string, headers map[string]string) (int, models.PostResponse, apiResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	var res apiResponse
+	var post models.PostResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	json.Unmarshal(res.Data, &post)
+	return rec.Code, post, res
+}
+
+func TestPatchPost(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World", Tags: []string{"go", "web"}})
+	path := "/api/v1/posts/" + created.ID
+
+	code, post, res := s.patch(t, path, "application/merge-patch+json", `{"title":"Merged"}`, nil)
+	if code != http.StatusOK {
+		t.Fatalf("merge patch: status %d, error %q", code, res.Detail)
+	}
+	if post.Title != "Merged" || post.Content != "World" || len(post.Tags) != 2 {
+		t.Errorf("merge patch returned %+v", post)
+	}
+
+	code, post, res = s.patch(t, path, "application/json-patch+json",
+		`[{"op":"add","path":"/tags/-","value":"db"},{"op":"test","path":"/tags/0","value":"go"},{"op":"remove","path":"/tags/0"}]`,
+		map[string]string{"If-Match": `"2"`})
+	if code != http.StatusOK {
+		t.Fatalf("json patch: status %d, error %q", code, res.Detail)
+	}
+	if want := []string{"web", "db"}; len(post.Tags) != 2 || post.Tags[0] != want[0] || post.Tags[1] != want[1] {
+		t.Errorf("tags = %v, want %v", post.Tags, want)
+	}
+
+	code, post, res = s.patch(t, path, "application/merge-patch+json", `{"add_tags":["go","web"],"remove_tags":["db","rust"]}`, nil)
+	if code != http.StatusOK {
+		t.Fatalf("tag edits: status %d, error %q", code, res.Detail)
+	}
+	if want := []string{"web", "go"}; len(post.Tags) != 2 || post.Tags[0] != want[0] || post.Tags[1] != want[1] {
+		t.Errorf("tags = %v, want %v", post.Tags, want)
+	}
+
+	code, post, _ = s.patch(t, path, "application/merge-patch+json", `{"tags":null}`, nil)
+	if code != http.StatusOK || len(post.Tags) != 0 {
+		t.Errorf("clearing tags: status %d, tags %v", code, post.Tags)
+	}
+}
+
+func TestPatchPostErrors(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World"})
+	path := "/api/v1/posts/" + created.ID
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		ifMatch     string
+		status      int
+		code        string
+	}{
+		{"plain json", "application/json", `{"title":"x"}`, "", http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"malformed", "application/json-patch+json", `{"op":"add"}`, "", http.StatusBadRequest, "invalid_request"},
+		{"unknown field", "application/merge-patch+json", `{"author":"me"}`, "", http.StatusBadRequest, "invalid_patch"},
+		{"remove title", "application/merge-patch+json", `{"title":null}`, "", http.StatusBadRequest, "invalid_patch"},
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/title","value":"Bye"}]`, "", http.StatusConflict, "patch_test_failed"},
+		{"stale version", "application/merge-patch+json", `{"title":"x"}`, `"9"`, http.StatusPreconditionFailed, "post_modified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.ifMatch != "" {
+				headers["If-Match"] = tt.ifMatch
+			}
+			code, _, res := s.patch(t, path, tt.contentType, tt.body, headers)
+			if code != tt.status || res.Code != tt.code {
+				t.Errorf("status %d code %q, want %d %q", code, res.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func (s *testServer) bulk(t *testing.T, query, body string) (int, models.BulkResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/posts/_bulk"+query, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	var res apiResponse
+	var bulk models.BulkResponse
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	json.Unmarshal(res.Data, &bulk)
+	return rec.Code, bulk
+}
+
+func statuses(items []models.BulkItemResult) []int {
+	codes := make([]int, len(items))
+	for i, item := range items {
+		codes[i] = item.Status
+	}
+	return codes
+}
+
+func TestBulkPosts(t *testing.T) {
+	s := newTestServer(t)
+	existing := s.createPost(t, models.PostCreateRequest{Title: "Old", Content: "x", Tags: []string{"go"}})
+	doomed := s.createPost(t, models.PostCreateRequest{Title: "Doomed", Content: "x"})
+	imported := uuid.NewString()
+
+	body := `{"op":"create","title":"One","content":"x","tags":["go"]}
+
+{"op":"create","id":"` + imported + `","title":"Two","content":"x"}
+{"op":"update","id":"` + existing.ID + `","version":1,"title":"Renamed"}
+{"op":"delete","id":"` + doomed.ID + `"}
+`
+	code, res := s.bulk(t, "", body)
+	if code != http.StatusOK || res.Errors {
+		t.Fatalf("status %d, items %+v", code, res.Items)
+	}
+	want := []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusOK}
+	if got := statuses(res.Items); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if res.Items[1].ID != imported {
+		t.Errorf("create kept id %q, want %q", res.Items[1].ID, imported)
+	}
+	if res.Items[2].Version != 2 {
+		t.Errorf("updated version = %d, want 2", res.Items[2].Version)
+	}
+	if n := s.posts.Len(); n != 3 {
+		t.Errorf("store has %d posts, want 3", n)
+	}
+	if n := s.index.BulkRequests(); n != 1 {
+		t.Errorf("made %d bulk index requests, want 1", n)
+	}
+
+	code, search := s.do(t, http.MethodGet, "/api/v1/posts/search?q=renamed", nil)
+	var result models.PostSearchResponse
+	json.Unmarshal(search.Data, &result)
+	if code != http.StatusOK || result.TotalCount != 1 {
+		t.Errorf("renamed post not indexed: %+v", result)
+	}
+}
+
+func TestBulkPostsAtomicRollback(t *testing.T) {
+	s := newTestServer(t)
+
+	body := `{"op":"create","title":"One","content":"x"}
+{"op":"delete","id":"` + uuid.NewString() + `"}
+{"op":"create","title":"Three","content":"x"}
+`
+	code, res := s.bulk(t, "", body)
+	if code != http.StatusOK || !res.Errors {
+		t.Fatalf("status %d, errors %v", code, res.Errors)
+	}
+	want := []int{http.StatusConflict, http.StatusNotFound, http.StatusConflict}
+	if got := statuses(res.Items); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if res.Items[1].Code != "post_not_found" || res.Items[0].Code != "bulk_rolled_back" {
+		t.Errorf("codes = %q, %q", res.Items[0].Code, res.Items[1].Code)
+	}
+	if n := s.posts.Len(); n != 0 {
+		t.Errorf("store has %d posts after rollback", n)
+	}
+	if n := s.index.BulkRequests(); n != 0 {
+		t.Errorf("indexed a rolled back bulk")
+	}
+}
+
+func TestBulkPostsPartial(t *testing.T) {
+	s := newTestServer(t)
+
+	body := `{"op":"create","title":"One","content":"x"}
+{"op":"update","title":"No id"}
+{"op":"create","title":"Three","content":"x"}
+`
+	code, res := s.bulk(t, "?atomic=false", body)
+	if code != http.StatusOK || !res.Errors || res.Atomic {
+		t.Fatalf("status %d, response %+v", code, res)
+	}
+	want := []int{http.StatusCreated, http.StatusBadRequest, http.StatusCreated}
+	if got := statuses(res.Items); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if n := s.posts.Len(); n != 2 {
+		t.Errorf("store has %d posts, want 2", n)
+	}
+
+	if code, _ := s.bulk(t, "", `{"op":"create","title":`); code != http.StatusBadRequest {
+		t.Errorf("malformed line: status %d, want 400", code)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{"ndjson", "markdown"} {
+		t.Run(format, func(t *testing.T) {
+			src := newTestServer(t)
+			created := src.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "World", Tags: []string{"go"}})
+
+			export := src.doWithHeaders(t, http.MethodGet, "/api/v1/posts/export?format="+format, nil, nil)
+			if export.Code != http.StatusOK {
+				t.Fatalf("export: status %d", export.Code)
+			}
+
+			dst := newTestServer(t)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/posts/import?format="+format, bytes.NewReader(export.Body.Bytes()))
+			rec := httptest.NewRecorder()
+			dst.router.ServeHTTP(rec, req)
+			var res apiResponse
+			var result models.BulkResponse
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			json.Unmarshal(res.Data, &result)
+			if rec.Code != http.StatusOK || result.Errors {
+				t.Fatalf("import: status %d, %s", rec.Code, rec.Body.String())
+			}
+
+			code, got := dst.do(t, http.MethodGet, "/api/v1/posts/"+created.ID, nil)
+			var post models.PostResponse
+			json.Unmarshal(got.Data, &post)
+			if code != http.StatusOK || post.Title != "Hello" || post.Content != "World" {
+				t.Fatalf("imported post: status %d, %+v", code, post)
+			}
+			if !post.CreatedAt.Equal(created.CreatedAt) {
+				t.Errorf("created_at = %v, want the original %v", post.CreatedAt, created.CreatedAt)
+			}
+			if _, search := dst.do(t, http.MethodGet, "/api/v1/posts/search?q=hello", nil); !bytes.Contains(search.Data, []byte(created.ID)) {
+				t.Error("imported post is not searchable")
+			}
+
+			// Importing the same file again conflicts instead of duplicating.
+			rec = httptest.NewRecorder()
+			dst.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/posts/import?format="+format, bytes.NewReader(export.Body.Bytes())))
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			json.Unmarshal(res.Data, &result)
+			if len(result.Items) != 1 || result.Items[0].Status != http.StatusConflict {
+				t.Errorf("re-import items = %+v, want one 409", result.Items)
+			}
+		})
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	s := newTestServer(t)
+	first := s.createPost(t, models.PostCreateRequest{Title: "Xin chào", Content: "First", Tags: []string{"go"}})
+	s.createPost(t, models.PostCreateRequest{Title: "Second", Content: "Second"})
+
+	rec := s.doWithHeaders(t, http.MethodGet, "/feed.json", nil, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/feed+json") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var jsonFeed struct {
+		FeedURL string `json:"feed_url"`
+		Items   []struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &jsonFeed); err != nil {
+		t.Fatalf("invalid feed: %v", err)
+	}
+	if jsonFeed.FeedURL != "https://blog.example/feed.json" || len(jsonFeed.Items) != 2 {
+		t.Fatalf("feed = %+v", jsonFeed)
+	}
+	if item := jsonFeed.Items[1]; item.ID != "urn:uuid:"+first.ID || item.URL != "https://blog.example/posts/xin-chao" {
+		t.Errorf("oldest item = %+v", item)
+	}
+
+	if lm := rec.Header().Get("Last-Modified"); lm == "" {
+		t.Error("no Last-Modified header")
+	}
+	if rec := s.doWithHeaders(t, http.MethodGet, "/feed.json", nil, map[string]string{"If-None-Match": rec.Header().Get("ETag")}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", rec.Code)
+	}
+
+	// Backdate the tag feed's change so a later change is a whole second
+	// newer than what the client saw.
+	lastChanged := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	s.cache.SetJSON(context.Background(), "feed:changed:go", lastChanged, 0)
+	rec = s.doWithHeaders(t, http.MethodGet, "/tags/go/feed.atom", nil, nil)
+	lastModified := rec.Header().Get("Last-Modified")
+	if lastModified != lastChanged.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q, want %q", lastModified, lastChanged.Format(http.TimeFormat))
+	}
+	if rec := s.doWithHeaders(t, http.MethodGet, "/tags/go/feed.atom", nil, map[string]string{"If-Modified-Since": lastModified}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status %d, want 304", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "<title>Xin chào</title>") || strings.Contains(rec.Body.String(), "<title>Second</title>") {
+		t.Errorf("tag feed = %s", rec.Body.String())
+	}
+	if !s.cache.Cached("feed:posts:go") {
+		t.Fatal("tag feed not cached")
+	}
+
+	// Removing the tag must drop the post from the cached tag feed.
+	s.do(t, http.MethodPut, "/api/v1/posts/"+first.ID, map[string]interface{}{"tags": []string{"web"}})
+	if s.cache.Cached("feed:posts:go") || s.cache.Cached("feed:posts:") {
+		t.Error("feeds still cached after update")
+	}
+	rec = s.doWithHeaders(t, http.MethodGet, "/tags/go/feed.atom", nil, map[string]string{"If-Modified-Since": lastModified})
+	if rec.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since after the post left: status %d, want 200", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "<entry>") {
+		t.Errorf("tag feed still lists the post: %s", rec.Body.String())
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm == lastModified {
+		t.Errorf("Last-Modified unchanged after the post left: %q", lm)
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	s := newTestServer(t)
+	s.createPost(t, models.PostCreateRequest{Title: "Xin chào", Content: "First"})
+
+	rec := s.doWithHeaders(t, http.MethodGet, "/sitemap.xml", nil, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<loc>https://blog.example/sitemaps/posts-1.xml</loc>") {
+		t.Fatalf("index: status %d: %s", rec.Code, rec.Body.String())
+	}
+	lastModified := rec.Header().Get("Last-Modified")
+	if rec := s.doWithHeaders(t, http.MethodGet, "/sitemap.xml", nil, map[string]string{"If-Modified-Since": lastModified}); rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status %d, want 304", rec.Code)
+	}
+
+	rec = s.doWithHeaders(t, http.MethodGet, "/sitemaps/posts-1.xml", nil, nil)
+	if !strings.Contains(rec.Body.String(), "<loc>https://blog.example/posts/xin-chao</loc>") {
+		t.Errorf("sitemap = %s", rec.Body.String())
+	}
+
+	for _, path := range []string{"/sitemaps/posts-2.xml", "/sitemaps/posts-01.xml", "/sitemaps/tags-1.xml"} {
+		if rec := s.doWithHeaders(t, http.MethodGet, path, nil, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestPostContentHTML(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "*hi* <script>alert(1)</script>"})
+	path := "/api/v1/posts/" + created.ID
+	if created.ContentFormat != "markdown" || created.ContentHTML != "" {
+		t.Errorf("created = %+v, want markdown without content_html", created)
+	}
+
+	var got models.PostResponse
+	_, res := s.do(t, http.MethodGet, path+"?include=content_html", nil)
+	json.Unmarshal(res.Data, &got)
+	if got.ContentHTML != "<p><em>hi</em> </p>\n" {
+		t.Errorf("content_html = %q", got.ContentHTML)
+	}
+
+	_, res = s.do(t, http.MethodPut, path+"?include=content_html", map[string]interface{}{"content_format": "plain"})
+	json.Unmarshal(res.Data, &got)
+	if got.ContentFormat != "plain" || got.ContentHTML != "<p>*hi* &lt;script&gt;alert(1)&lt;/script&gt;</p>\n" {
+		t.Errorf("after format change = %+v", got)
+	}
+
+	code, res := s.do(t, http.MethodPost, "/api/v1/posts", map[string]interface{}{"title": "x", "content": "y", "content_format": "rst"})
+	if code != http.StatusBadRequest {
+		t.Errorf("create with unknown format: status %d", code)
+	}
+	code, _, res = s.patch(t, path, "application/merge-patch+json", `{"content_format":"rst"}`, nil)
+	if code != http.StatusBadRequest || res.Code != "invalid_patch" {
+		t.Errorf("patch with unknown format: status %d, code %q", code, res.Code)
+	}
+}
+
+func TestPostSummary(t *testing.T) {
+	s := newTestServer(t)
+	created := s.createPost(t, models.PostCreateRequest{Title: "Hello", Content: "## Intro\n\nSome **bold** words.", Tags: []string{"go"}})
+	if created.Excerpt != "Intro Some bold words." || created.WordCount != 4 || created.ReadingTimeMinutes != 1 {
+		t.Errorf("created = %+v", created)
+	}
+	if len(created.TOC) != 1 || created.TOC[0] != (models.TOCEntry{Level: 2, Text: "Intro", ID: "intro"}) {
+		t.Errorf("toc = %+v", created.TOC)
+	}
+
+	s.do(t, http.MethodPut, "/api/v1/posts/"+created.ID, map[string]interface{}{"content": "No headings."})
+	_, res := s.do(t, http.MethodGet, "/api/v1/posts/search-by-tag?tag=go", nil)
+	var posts []models.PostResponse
+	json.Unmarshal(res.Data, &posts)
+	if len(posts) != 1 || posts[0].Excerpt != "No headings." || posts[0].TOC == nil || len(posts[0].TOC) != 0 {
+		t.Errorf("listed = %+v", posts)
+	}
+}
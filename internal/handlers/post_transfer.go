@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"blog/internal/models"
+	"blog/internal/transfer"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize bounds an uploaded import file.
+const maxImportSize = 64 << 20
+
+// ExportPosts streams every post as NDJSON (format=ndjson, the default) or
+// as a zip of Markdown files (format=markdown).
+func (h *PostHandler) ExportPosts(c *gin.Context) {
+	var w transfer.Writer
+	switch format := c.DefaultQuery("format", transfer.FormatNDJSON); format {
+	case transfer.FormatNDJSON:
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="posts.ndjson"`)
+		w = transfer.NewNDJSONWriter(c.Writer)
+	case transfer.FormatMarkdown:
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="posts.zip"`)
+		w = transfer.NewMarkdownWriter(c.Writer)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported export format "+strconv.Quote(format), nil)
+		return
+	}
+
+	err := h.postService.ExportPosts(c.Request.Context(), func(post *models.Post) error {
+		return w.Write(transfer.FromPost(post))
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Error(err).SetMeta("Failed to export posts")
+			return
+		}
+		// The body is partly sent; all that is left is to log it.
+		log.Printf("[ERROR] Failed to export posts: %v", err)
+	}
+}
+
+// ImportPosts creates posts from an NDJSON or Markdown zip export or a
+// WordPress WXR file. The format comes from the format parameter or the
+// Content-Type. Imports go through the bulk path, so posts are logged and
+// indexed like any other create; atomic works as for bulk requests.
+func (h *PostHandler) ImportPosts(c *gin.Context) {
+	atomic, err := strconv.ParseBool(c.DefaultQuery("atomic", "true"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid atomic parameter", err)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = importFormat(c.ContentType())
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import file is too large", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	var records []transfer.Record
+	switch format {
+	case transfer.FormatNDJSON:
+		records, err = transfer.ReadNDJSON(bytes.NewReader(body))
+	case transfer.FormatMarkdown:
+		records, err = transfer.ReadMarkdownZip(body)
+	case transfer.FormatWXR:
+		records, err = transfer.ReadWXR(bytes.NewReader(body))
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported import format "+strconv.Quote(format), nil)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid import file", err)
+		return
+	}
+	if len(records) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Import file has no posts", nil)
+		return
+	}
+
+	ops := make([]models.BulkOperation, len(records))
+	for i, record := range records {
+		ops[i] = record.Operation()
+	}
+	results := h.postService.Bulk(c.Request.Context(), ops, atomic)
+	utils.SuccessResponse(c, http.StatusOK, "Import processed", bulkResponse(ops, results, atomic))
+}
+
+func importFormat(contentType string) string {
+	switch contentType {
+	case "application/x-ndjson":
+		return transfer.FormatNDJSON
+	case "application/zip":
+		return transfer.FormatMarkdown
+	case "application/xml", "text/xml", "application/rss+xml":
+		return transfer.FormatWXR
+	}
+	return ""
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"blog/internal/config"
 	"blog/internal/models"
 	"blog/internal/services"
 	"blog/internal/utils"
@@ -11,11 +13,12 @@ import (
 )
 
 type SearchHandler struct {
-	searchService *services.SearchService
+	searchService services.PostIndex
+	cfg           *config.PostsConfig
 }
 
-func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
-	return &SearchHandler{searchService: searchService}
+func NewSearchHandler(searchService services.PostIndex, cfg *config.PostsConfig) *SearchHandler {
+	return &SearchHandler{searchService: searchService, cfg: cfg}
 }
 
 func (h *SearchHandler) SearchPosts(c *gin.Context) {
@@ -27,7 +30,20 @@ func (h *SearchHandler) SearchPosts(c *gin.Context) {
 
 	result, err := h.searchService.SearchPosts(c.Request.Context(), &req)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to search posts", err)
+		c.Error(err).SetMeta("Failed to search posts")
+		return
+	}
+
+	// No Last-Modified: deleting a post changes the result without making
+	// any remaining post newer.
+	etag := searchETag(result)
+	keys := []string{surrogateKeyPosts}
+	for _, post := range result.Posts {
+		keys = append(keys, postSurrogateKey(post.ID))
+	}
+	setCacheHeaders(c, h.cfg.SearchCacheControl, h.cfg.SurrogateKeyHeader, etag, time.Time{}, keys...)
+	if notModified(c.Request, etag, time.Time{}) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"blog/internal/config"
+	"blog/internal/services"
+	"blog/internal/sitemap"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SitemapHandler struct {
+	sitemapService *services.SitemapService
+	site           *config.SiteConfig
+}
+
+func NewSitemapHandler(sitemapService *services.SitemapService, site *config.SiteConfig) *SitemapHandler {
+	return &SitemapHandler{sitemapService: sitemapService, site: site}
+}
+
+func (h *SitemapHandler) Index(c *gin.Context) {
+	sm, err := h.sitemapService.Sitemap(c.Request.Context())
+	if err != nil {
+		c.Error(err).SetMeta("Failed to load sitemap")
+		return
+	}
+	h.serve(c, sm, sm.Index)
+}
+
+// Chunk serves /sitemaps/posts-<n>.xml.
+func (h *SitemapHandler) Chunk(c *gin.Context) {
+	file := c.Param("file")
+	var n int
+	if _, err := fmt.Sscanf(file, "posts-%d.xml", &n); err != nil || fmt.Sprintf("posts-%d.xml", n) != file {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sitemap not found", nil)
+		return
+	}
+
+	sm, err := h.sitemapService.Sitemap(c.Request.Context())
+	if err != nil {
+		c.Error(err).SetMeta("Failed to load sitemap")
+		return
+	}
+	if n < 1 || n > len(sm.Chunks) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sitemap not found", nil)
+		return
+	}
+	h.serve(c, sm, sm.Chunks[n-1])
+}
+
+func (h *SitemapHandler) serve(c *gin.Context, sm *services.Sitemap, body []byte) {
+	etag := bodyETag(body)
+	setCacheHeaders(c, h.site.SitemapCacheControl, "", etag, sm.Generated)
+	if notModified(c.Request, etag, sm.Generated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, sitemap.ContentType, body)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+
+	"blog/internal/models"
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to create webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Webhook created successfully", webhook)
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.Error(err).SetMeta("Failed to list webhooks")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhooks retrieved successfully", webhooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook ID", err)
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		c.Error(err).SetMeta("Failed to delete webhook")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook deleted successfully", nil)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook ID", err)
+		return
+	}
+
+	var query models.WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), id, &query)
+	if err != nil {
+		c.Error(err).SetMeta("Failed to list deliveries")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Deliveries retrieved successfully", deliveries)
+}
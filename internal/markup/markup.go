@@ -0,0 +1,114 @@
+// Package markup renders post content to HTML that is safe to embed in a
+// page: whatever the source format, the output only contains allowlisted
+// elements, attributes and URL schemes.
+package markup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"blog/internal/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+const (
+	Markdown = "markdown"
+	HTML     = "html"
+	Plain    = "plain"
+)
+
+var ErrUnknownFormat = errors.New("unknown content format")
+
+// Valid reports whether format is one Render accepts.
+func Valid(format string) bool {
+	return format == Markdown || format == HTML || format == Plain
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+	),
+	// Raw HTML is passed through and then sanitized like HTML content.
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements(
+		"p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"blockquote", "pre", "code", "em", "strong", "del", "s", "sub", "sup",
+		"ul", "ol", "li", "table", "thead", "tbody", "tr", "th", "td",
+	)
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("src", "alt", "title").OnElements("img")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(false)
+	return p
+}
+
+// Rendered is content rendered to sanitized HTML, with a summary derived
+// from that HTML so it does not depend on the source format.
+type Rendered struct {
+	HTML               string
+	Excerpt            string
+	WordCount          int
+	ReadingTimeMinutes int
+	TOC                models.TableOfContents
+}
+
+// Render converts content in the given format to sanitized HTML, giving
+// every heading an id for the table of contents to link to.
+func Render(format, content string) (*Rendered, error) {
+	var unsafe string
+	switch format {
+	case Markdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return nil, fmt.Errorf("failed to render markdown: %w", err)
+		}
+		unsafe = buf.String()
+	case HTML:
+		unsafe = content
+	case Plain:
+		unsafe = renderPlain(content)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return summarize(policy.Sanitize(unsafe)), nil
+}
+
+// renderPlain turns blank-line separated paragraphs into <p> elements and
+// the remaining line breaks into <br>.
+func renderPlain(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var b strings.Builder
+	for _, para := range strings.Split(content, "\n\n") {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
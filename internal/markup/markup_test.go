@@ -0,0 +1,68 @@
+package markup_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"blog/internal/markup"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		want    string
+	}{
+		{"markdown", markup.Markdown, "# Title\n\nSome *text* and `code`.", "<h1 id=\"title\">Title</h1>\n<p>Some <em>text</em> and <code>code</code>.</p>\n"},
+		{"markdown link", markup.Markdown, "[go](https://go.dev)", `<p><a href="https://go.dev" rel="nofollow">go</a></p>` + "\n"},
+		{"markdown code class", markup.Markdown, "```go\nx := 1\n```", `<pre><code class="language-go">x := 1` + "\n</code></pre>\n"},
+		{"markdown table", markup.Markdown, "| a |\n|:-:|\n| b |", "<table>\n<thead>\n<tr>\n<th align=\"center\">a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"center\">b</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"html", markup.HTML, `<p class="x">Hi <b>there</b></p>`, "<p>Hi there</p>"},
+		{"plain", markup.Plain, "a < b\nc\n\nd", "<p>a &lt; b<br>\nc</p>\n<p>d</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := markup.Render(tt.format, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.HTML != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderStripsScripts(t *testing.T) {
+	attacks := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror="alert(1)">`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`[x](javascript:alert(1))`,
+		`<iframe src="https://evil.example"></iframe>`,
+		`<p style="background:url(javascript:alert(1))">x</p>`,
+		`<svg onload=alert(1)>`,
+	}
+	for _, format := range []string{markup.Markdown, markup.HTML} {
+		for _, attack := range attacks {
+			got, err := markup.Render(format, attack)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lower := strings.ToLower(got.HTML)
+			for _, bad := range []string{"<script", "onerror", "onload", `="javascript:`, "<iframe", "style=", "<svg"} {
+				if strings.Contains(lower, bad) {
+					t.Errorf("%s %q rendered to %q", format, attack, got)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, err := markup.Render("rst", "x"); !errors.Is(err, markup.ErrUnknownFormat) {
+		t.Errorf("error = %v, want ErrUnknownFormat", err)
+	}
+}
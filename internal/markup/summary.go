@@ -0,0 +1,139 @@
+package markup
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"blog/internal/models"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// ExcerptLength is the maximum length of an excerpt in characters,
+	// not counting the ellipsis.
+	ExcerptLength = 200
+	// WordsPerMinute is the reading speed reading times assume.
+	WordsPerMinute = 200
+)
+
+// blockElements separate words in the extracted text; inline elements
+// such as <em> do not.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true, atom.Tr: true,
+	atom.Th: true, atom.Td: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// summarize adds ids to the headings of sanitized HTML and derives the
+// summary fields from its text.
+func summarize(sanitized string) *Rendered {
+	r := &Rendered{TOC: models.TableOfContents{}}
+	var out, text, heading, headingText strings.Builder
+	ids := make(map[string]bool)
+	level := 0
+
+	z := html.NewTokenizer(strings.NewReader(sanitized))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		// Token unescapes text in place, so copy the raw bytes first.
+		raw := append([]byte(nil), z.Raw()...)
+		token := z.Token()
+
+		w := &out
+		if level > 0 {
+			w = &heading
+		}
+		switch tt {
+		case html.TextToken:
+			text.WriteString(token.Data)
+			if level > 0 {
+				headingText.WriteString(token.Data)
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			if blockElements[token.DataAtom] {
+				text.WriteByte(' ')
+			}
+			if l := headingLevels[token.DataAtom]; l > 0 && tt == html.StartTagToken && level == 0 {
+				level = l
+				heading.Reset()
+				headingText.Reset()
+				continue
+			}
+			if headingLevels[token.DataAtom] == level && tt == html.EndTagToken && level > 0 {
+				entry := models.TOCEntry{Level: level, Text: collapseSpace(headingText.String())}
+				entry.ID = uniqueID(ids, entry.Text)
+				r.TOC = append(r.TOC, entry)
+				fmt.Fprintf(&out, `<h%d id="%s">%s`, level, entry.ID, heading.String())
+				out.Write(raw)
+				level = 0
+				continue
+			}
+		}
+		w.Write(raw)
+	}
+	if level > 0 {
+		// Unclosed heading: keep its content without an anchor.
+		fmt.Fprintf(&out, "<h%d>%s", level, heading.String())
+	}
+	r.HTML = out.String()
+
+	plain := collapseSpace(text.String())
+	r.Excerpt = excerpt(plain, ExcerptLength)
+	if plain != "" {
+		r.WordCount = len(strings.Fields(plain))
+		r.ReadingTimeMinutes = (r.WordCount + WordsPerMinute - 1) / WordsPerMinute
+	}
+	return r
+}
+
+// uniqueID slugs a heading, numbering repeated headings like GitHub does.
+func uniqueID(ids map[string]bool, text string) string {
+	base := models.Slugify(text)
+	if base == "" {
+		base = "heading"
+	}
+	id := base
+	for i := 1; ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	ids[id] = true
+	return id
+}
+
+// excerpt cuts text to at most n characters, at a word boundary when
+// there is one.
+func excerpt(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	cut := text
+	for i := range text {
+		if n == 0 {
+			cut = text[:i]
+			break
+		}
+		n--
+	}
+	// The cut falls inside a word unless the next character is a space.
+	if !strings.HasPrefix(text[len(cut):], " ") {
+		if i := strings.LastIndexByte(cut, ' '); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRight(cut, " ,;:.-") + "…"
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,58 @@
+package markup_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"blog/internal/markup"
+	"blog/internal/models"
+)
+
+func TestRenderTOC(t *testing.T) {
+	got, err := markup.Render(markup.Markdown, "# Giới thiệu\n\ntext\n\n## Cài *đặt*\n\n## Cài đặt\n\n### !!!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := models.TableOfContents{
+		{Level: 1, Text: "Giới thiệu", ID: "gioi-thieu"},
+		{Level: 2, Text: "Cài đặt", ID: "cai-dat"},
+		{Level: 2, Text: "Cài đặt", ID: "cai-dat-1"},
+		{Level: 3, Text: "!!!", ID: "heading"},
+	}
+	if !reflect.DeepEqual(got.TOC, want) {
+		t.Errorf("toc = %+v, want %+v", got.TOC, want)
+	}
+	if !strings.Contains(got.HTML, `<h2 id="cai-dat">Cài <em>đặt</em></h2>`) {
+		t.Errorf("heading without anchor: %s", got.HTML)
+	}
+}
+
+func TestRenderSummary(t *testing.T) {
+	got, err := markup.Render(markup.Markdown, "Some **bold** text with a [link](https://go.dev).\n\n- one\n- two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Excerpt != "Some bold text with a link. one two" {
+		t.Errorf("excerpt = %q", got.Excerpt)
+	}
+	if got.WordCount != 8 || got.ReadingTimeMinutes != 1 {
+		t.Errorf("word count %d, reading time %d", got.WordCount, got.ReadingTimeMinutes)
+	}
+
+	long := strings.Repeat("word ", 450)
+	got, _ = markup.Render(markup.Plain, long)
+	if got.WordCount != 450 || got.ReadingTimeMinutes != 3 {
+		t.Errorf("word count %d, reading time %d", got.WordCount, got.ReadingTimeMinutes)
+	}
+	if !strings.HasSuffix(got.Excerpt, "word…") || utf8.RuneCountInString(got.Excerpt) > markup.ExcerptLength+1 {
+		t.Errorf("excerpt = %q", got.Excerpt)
+	}
+
+	got, _ = markup.Render(markup.HTML, "")
+	if got.Excerpt != "" || got.WordCount != 0 || got.ReadingTimeMinutes != 0 || got.TOC == nil {
+		t.Errorf("empty content = %+v", got)
+	}
+}
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+)
+
+type viewKey struct {
+	postID uuid.UUID
+	day    string
+}
+
+// Cache is a services.PostCache, services.ViewCounter and
+// services.JSONCache; TTLs are ignored. Setting
+// Unavailable makes every call fail with services.ErrCacheUnavailable, as
+// CacheService does while its circuit breaker is open.
+type Cache struct {
+	mu          sync.Mutex
+	posts       map[uuid.UUID]models.Post
+	views       map[viewKey]int64
+	visitors    map[viewKey]map[string]struct{}
+	values      map[string][]byte
+	Unavailable bool
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		posts:    make(map[uuid.UUID]models.Post),
+		views:    make(map[viewKey]int64),
+		visitors: make(map[viewKey]map[string]struct{}),
+		values:   make(map[string][]byte),
+	}
+}
+
+func (c *Cache) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return nil, services.ErrCacheUnavailable
+	}
+	post, ok := c.posts[id]
+	if !ok {
+		return nil, services.ErrCacheMiss
+	}
+	return clonePost(post), nil
+}
+
+func (c *Cache) SetPost(ctx context.Context, post *models.Post) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	c.posts[post.ID] = *clonePost(*post)
+	return nil
+}
+
+func (c *Cache) DeletePost(ctx context.Context, id uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	delete(c.posts, id)
+	return nil
+}
+
+func (c *Cache) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	data, ok := c.values[key]
+	if !ok {
+		return services.ErrCacheMiss
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *Cache) SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	c.values[key] = data
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	for _, key := range keys {
+		delete(c.values, key)
+	}
+	return nil
+}
+
+// Cached reports whether a JSON value is stored under key.
+func (c *Cache) Cached(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.values[key]
+	return ok
+}
+
+func (c *Cache) RecordPostView(ctx context.Context, id uuid.UUID, day time.Time, visitor string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	key := viewKey{postID: id, day: day.UTC().Format("2006-01-02")}
+	c.views[key]++
+	if visitor != "" {
+		if c.visitors[key] == nil {
+			c.visitors[key] = make(map[string]struct{})
+		}
+		c.visitors[key][visitor] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Cache) PopPostViews(ctx context.Context, count int64) ([]models.PostStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return nil, services.ErrCacheUnavailable
+	}
+	var stats []models.PostStats
+	for key, views := range c.views {
+		if int64(len(stats)) >= count {
+			break
+		}
+		day, _ := time.Parse("2006-01-02", key.day)
+		stats = append(stats, models.PostStats{
+			PostID:         key.postID,
+			Day:            day,
+			Views:          views,
+			UniqueVisitors: int64(len(c.visitors[key])),
+		})
+		delete(c.views, key)
+	}
+	return stats, nil
+}
+
+func (c *Cache) RestorePostViews(ctx context.Context, stats []models.PostStats) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Unavailable {
+		return services.ErrCacheUnavailable
+	}
+	for _, st := range stats {
+		c.views[viewKey{postID: st.PostID, day: st.Day.Format("2006-01-02")}] += st.Views
+	}
+	return nil
+}
+
+// CachedPost reports whether the post is currently cached.
+func (c *Cache) CachedPost(id uuid.UUID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.posts[id]
+	return ok
+}
+
+// PendingViews returns the buffered, unflushed views of the post.
+func (c *Cache) PendingViews(id uuid.UUID) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	for key, views := range c.views {
+		if key.postID == id {
+			total += views
+		}
+	}
+	return total
+}
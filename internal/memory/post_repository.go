@@ -0,0 +1,231 @@
+// Package memory provides in-memory implementations of the storage, cache
+// and search interfaces in package services, for tests and local runs
+// without PostgreSQL, Redis or Elasticsearch.
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"blog/internal/database"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	_ services.UnitOfWork     = (*UnitOfWork)(nil)
+	_ services.PostRepository = (*PostRepository)(nil)
+	_ services.PostCache      = (*Cache)(nil)
+	_ services.ViewCounter    = (*Cache)(nil)
+	_ services.PostIndex      = (*SearchIndex)(nil)
+)
+
+type txKey struct{}
+
+// UnitOfWork restores the repository to its state before Do when fn fails.
+// Transactions are not isolated from concurrent writers.
+type UnitOfWork struct {
+	posts *PostRepository
+}
+
+func NewUnitOfWork(posts *PostRepository) *UnitOfWork {
+	return &UnitOfWork{posts: posts}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	snapshot := u.posts.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		u.posts.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// PostRepository is a services.PostRepository backed by a map.
+type PostRepository struct {
+	mu    sync.RWMutex
+	posts map[uuid.UUID]models.Post
+}
+
+func NewPostRepository() *PostRepository {
+	return &PostRepository{posts: make(map[uuid.UUID]models.Post)}
+}
+
+func (s *PostRepository) Get(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	return clonePost(post), nil
+}
+
+func (s *PostRepository) ListByTag(ctx context.Context, tag string) ([]models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, post := range s.posts {
+		for _, t := range post.Tags {
+			if t == tag {
+				posts = append(posts, *clonePost(post))
+				break
+			}
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+	return posts, nil
+}
+
+func (s *PostRepository) ListRecent(ctx context.Context, tag string, limit int) ([]models.Post, error) {
+	s.mu.RLock()
+	posts := []models.Post{}
+	for _, post := range s.posts {
+		if tag == "" || slices.Contains(post.Tags, tag) {
+			posts = append(posts, *clonePost(post))
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID.String() > posts[j].ID.String()
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+func (s *PostRepository) Each(ctx context.Context, fn func(post *models.Post) error) error {
+	s.mu.RLock()
+	posts := make([]*models.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		posts = append(posts, clonePost(post))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.Before(posts[j].CreatedAt)
+		}
+		return posts[i].ID.String() < posts[j].ID.String()
+	})
+	for _, post := range posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostRepository) Create(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if post.ID == uuid.Nil {
+		post.ID = uuid.New()
+	}
+	if _, ok := s.posts[post.ID]; ok {
+		return database.ErrDuplicate
+	}
+	if post.Version == 0 {
+		post.Version = 1
+	}
+	s.posts[post.ID] = *clonePost(*post)
+	return nil
+}
+
+func (s *PostRepository) Update(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.posts[post.ID]
+	if !ok || existing.Version != post.Version {
+		return database.ErrStale
+	}
+	updated := *clonePost(*post)
+	updated.ViewCount = existing.ViewCount
+	updated.Version++
+	s.posts[post.ID] = updated
+	post.Version++
+	return nil
+}
+
+func (s *PostRepository) Delete(ctx context.Context, id uuid.UUID, version int64) (*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok && version != 0 {
+		return nil, database.ErrStale
+	}
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	if version != 0 && post.Version != version {
+		return nil, database.ErrStale
+	}
+	delete(s.posts, id)
+	return &post, nil
+}
+
+func (s *PostRepository) AddViews(ctx context.Context, id uuid.UUID, views int64) (*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+	post.ViewCount += views
+	s.posts[id] = post
+	return clonePost(post), nil
+}
+
+// Len returns the number of stored posts.
+func (s *PostRepository) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.posts)
+}
+
+func (s *PostRepository) snapshot() map[uuid.UUID]models.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := make(map[uuid.UUID]models.Post, len(s.posts))
+	for id, post := range s.posts {
+		posts[id] = post
+	}
+	return posts
+}
+
+func (s *PostRepository) restore(posts map[uuid.UUID]models.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.posts = posts
+}
+
+func clonePost(post models.Post) *models.Post {
+	if post.Tags != nil {
+		post.Tags = append(pq.StringArray{}, post.Tags...)
+	}
+	post.ActivityLogs = nil
+	return &post
+}
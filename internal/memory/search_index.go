@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// errIndexUnavailable is returned by writes while SearchIndex.Unavailable
+// is set.
+var errIndexUnavailable = errors.New("search index unavailable")
+
+// SearchIndex is a services.PostIndex. Queries match case-insensitive
+// substrings of the title, content and tags instead of analysed terms, and
+// results are not ranked by relevance. Setting Unavailable makes writes
+// fail, as while Elasticsearch is down, and Reject makes BulkIndex fail the
+// listed posts with the given status.
+type SearchIndex struct {
+	mu           sync.RWMutex
+	posts        map[uuid.UUID]models.Post
+	bulkRequests int
+	Unavailable  bool
+	Reject       map[uuid.UUID]int
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{posts: make(map[uuid.UUID]models.Post)}
+}
+
+func (s *SearchIndex) IndexPost(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Unavailable {
+		return errIndexUnavailable
+	}
+	s.posts[post.ID] = *clonePost(*post)
+	return nil
+}
+
+func (s *SearchIndex) DeletePost(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Unavailable {
+		return errIndexUnavailable
+	}
+	delete(s.posts, id)
+	return nil
+}
+
+func (s *SearchIndex) BulkIndex(ctx context.Context, upserts []*models.Post, deletes []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Unavailable {
+		return errIndexUnavailable
+	}
+	s.bulkRequests++
+
+	bulkErr := &services.BulkIndexError{Total: len(upserts) + len(deletes)}
+	for _, post := range upserts {
+		if status, ok := s.Reject[post.ID]; ok {
+			bulkErr.Failed = append(bulkErr.Failed, services.BulkItemError{ID: post.ID, Status: status})
+			continue
+		}
+		s.posts[post.ID] = *clonePost(*post)
+	}
+	for _, id := range deletes {
+		delete(s.posts, id)
+	}
+	if len(bulkErr.Failed) > 0 {
+		return bulkErr
+	}
+	return nil
+}
+
+// BulkRequests is the number of BulkIndex calls made so far.
+func (s *SearchIndex) BulkRequests() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bulkRequests
+}
+
+func (s *SearchIndex) UpdateViewCount(ctx context.Context, id uuid.UUID, viewCount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if post, ok := s.posts[id]; ok {
+		post.ViewCount = viewCount
+		s.posts[id] = post
+	}
+	return nil
+}
+
+func (s *SearchIndex) SearchPosts(ctx context.Context, req *models.PostSearchRequest) (*models.PostSearchResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	var tags []string
+	if req.Tags != "" {
+		for _, tag := range strings.Split(req.Tags, ",") {
+			tags = append(tags, strings.TrimSpace(tag))
+		}
+	}
+	query := strings.ToLower(req.Query)
+
+	s.mu.RLock()
+	var matches []models.Post
+	for _, post := range s.posts {
+		if matchesQuery(post, query) && hasAnyTag(post, tags) {
+			matches = append(matches, post)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if req.Sort == models.SortPopular && matches[i].ViewCount != matches[j].ViewCount {
+			return matches[i].ViewCount > matches[j].ViewCount
+		}
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+
+	posts := []models.PostResponse{}
+	for i := (req.Page - 1) * req.Limit; i < len(matches) && len(posts) < req.Limit; i++ {
+		posts = append(posts, matches[i].ToResponse())
+	}
+
+	return &models.PostSearchResponse{
+		Posts:      posts,
+		TotalCount: int64(len(matches)),
+		Page:       req.Page,
+		Limit:      req.Limit,
+	}, nil
+}
+
+func matchesQuery(post models.Post, query string) bool {
+	if query == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(post.Title), query) || strings.Contains(strings.ToLower(post.Content), query) {
+		return true
+	}
+	for _, tag := range post.Tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTag(post models.Post, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, want := range tags {
+		for _, tag := range post.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const actorContextKey = "actor"
+
+// AuthMiddleware identifies callers presenting "Authorization: Bearer <key>"
+// against the configured admin API keys. Anonymous requests pass through;
+// a key that does not match is rejected.
+func AuthMiddleware(apiKeys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header", nil)
+			c.Abort()
+			return
+		}
+
+		actor := ""
+		for name, key := range apiKeys {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+				actor = name
+			}
+		}
+		if actor == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid API key", nil)
+			c.Abort()
+			return
+		}
+
+		c.Set(actorContextKey, actor)
+		c.Request = c.Request.WithContext(services.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(actorContextKey) == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Admin API key required", nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
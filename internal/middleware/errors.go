@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMiddleware renders the last error a handler attached with c.Error
+// when the handler wrote no response itself. A string Meta on the error
+// describes the failed operation.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		ginErr := c.Errors.Last()
+		message, _ := ginErr.Meta.(string)
+		utils.ErrorResponse(c, StatusForError(ginErr.Err), message, ginErr.Err)
+	}
+}
+
+// StatusForError maps the services error kinds to HTTP status codes.
+func StatusForError(err error) int {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, services.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog/internal/middleware"
+	"blog/internal/services"
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", services.ErrPostNotFound, http.StatusNotFound, "post_not_found"},
+		{"wrapped conflict", fmt.Errorf("%w: post exists", services.ErrConflict), http.StatusConflict, "conflict"},
+		{"validation", services.ErrInvalidActivityQuery, http.StatusBadRequest, "invalid_activity_query"},
+		{"forbidden", services.ErrForbidden, http.StatusForbidden, "forbidden"},
+		{"unknown", errors.New("pq: relation \"posts\" does not exist"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.ErrorMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				c.Error(tt.err).SetMeta("Failed to do it")
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, utils.ProblemContentType) {
+				t.Errorf("Content-Type = %q, want %s", ct, utils.ProblemContentType)
+			}
+			var problem utils.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+			}
+			if problem.Status != tt.status || problem.Code != tt.code {
+				t.Errorf("problem = %+v, want status %d code %q", problem, tt.status, tt.code)
+			}
+			if tt.status >= http.StatusInternalServerError && strings.Contains(problem.Detail, tt.err.Error()) {
+				t.Errorf("internal error leaked in detail %q", problem.Detail)
+			}
+		})
+	}
+}
+
+func TestErrorMiddlewareKeepsWrittenResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.ErrorMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.Error(services.ErrPostNotFound)
+		c.Status(http.StatusAccepted)
+		c.Writer.WriteHeaderNow()
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want the handler's 202", rec.Code)
+	}
+}
@@ -4,16 +4,21 @@ import (
 	"fmt"
 	"time"
 
+	"blog/internal/requestmeta"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+const RequestIDHeader = "X-Request-ID"
+
 func CORSMiddleware() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -35,6 +40,26 @@ func LoggerMiddleware() gin.HandlerFunc {
 	})
 }
 
+// RequestMetaMiddleware assigns a request ID (reusing the caller's
+// X-Request-ID when present) and exposes client metadata to the services
+// through the request context.
+func RequestMetaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		c.Request = c.Request.WithContext(requestmeta.With(c.Request.Context(), requestmeta.Meta{
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestID,
+		}))
+		c.Next()
+	}
+}
+
 func RecoveryMiddleware() gin.HandlerFunc {
 	return gin.Recovery()
 }
\ No newline at end of file
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,12 +11,54 @@ import (
 )
 
 type ActivityLog struct {
-	ID       uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()" db:"id"`
-	Action   string    `json:"action" gorm:"type:varchar(50);not null" db:"action"`
-	PostID   uuid.UUID `json:"post_id" gorm:"type:uuid;not null;index" db:"post_id"`
-	LoggedAt time.Time `json:"logged_at" gorm:"autoCreateTime;index:,sort:desc" db:"logged_at"`
+	ID        uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()" db:"id"`
+	Action    string       `json:"action" gorm:"type:varchar(50);not null" db:"action"`
+	PostID    uuid.UUID    `json:"post_id" gorm:"type:uuid;not null;index" db:"post_id"`
+	PostTitle string       `json:"post_title" gorm:"type:varchar(255)" db:"post_title"`
+	Actor     string       `json:"actor" gorm:"type:varchar(100);index" db:"actor"`
+	ClientIP  string       `json:"client_ip" gorm:"type:varchar(45)" db:"client_ip"`
+	UserAgent string       `json:"user_agent" gorm:"type:text" db:"user_agent"`
+	RequestID string       `json:"request_id" gorm:"type:varchar(64);index" db:"request_id"`
+	Changes   FieldChanges `json:"changes,omitempty" gorm:"type:jsonb" db:"changes"`
+	LoggedAt  time.Time    `json:"logged_at" gorm:"primaryKey;autoCreateTime;index:,sort:desc" db:"logged_at"`
 
-	Post Post `json:"post,omitempty" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	// No foreign key: logs must outlive the post they describe.
+	Post Post `json:"post,omitempty" gorm:"foreignKey:PostID;constraint:-"`
+}
+
+// FieldChange holds the before and after value of a single field.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// FieldChanges is stored as JSONB, keyed by field name.
+type FieldChanges map[string]FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *FieldChanges) Scan(value interface{}) error {
+	if value == nil {
+		*c = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for FieldChanges: %T", value)
+	}
+
+	return json.Unmarshal(data, c)
 }
 
 func (ActivityLog) TableName() string {
@@ -28,6 +73,24 @@ func (a *ActivityLog) AfterAutoMigrate(tx *gorm.DB) error {
 	if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_activity_logs_logged_at ON activity_logs(logged_at DESC)").Error; err != nil {
 		return err
 	}
+
+	// Older schemas cascaded post deletes into activity_logs, wiping the
+	// delete_post entry together with the post.
+	if err := tx.Exec(`
+		DO $$
+		DECLARE c record;
+		BEGIN
+			FOR c IN
+				SELECT conname FROM pg_constraint
+				WHERE conrelid = 'activity_logs'::regclass
+				  AND confrelid = 'posts'::regclass
+				  AND contype = 'f'
+			LOOP
+				EXECUTE format('ALTER TABLE activity_logs DROP CONSTRAINT %I', c.conname);
+			END LOOP;
+		END $$`).Error; err != nil {
+		return err
+	}
 	
 	return nil
 }
@@ -47,3 +110,46 @@ func NewActivityLog(action string, postID uuid.UUID) *ActivityLog {
 		LoggedAt: time.Now(),
 	}
 }
+
+type ActivityLogQuery struct {
+	Action string    `form:"action"`
+	PostID string    `form:"post_id"`
+	Actor  string    `form:"actor"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int       `form:"limit"`
+	Cursor string    `form:"cursor"`
+}
+
+type ActivityLogResponse struct {
+	ID        string       `json:"id"`
+	Action    string       `json:"action"`
+	PostID    string       `json:"post_id"`
+	PostTitle string       `json:"post_title,omitempty"`
+	Actor     string       `json:"actor,omitempty"`
+	ClientIP  string       `json:"client_ip,omitempty"`
+	UserAgent string       `json:"user_agent,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Changes   FieldChanges `json:"changes,omitempty"`
+	LoggedAt  time.Time    `json:"logged_at"`
+}
+
+type ActivityLogListResponse struct {
+	Logs       []ActivityLogResponse `json:"logs"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+func (a *ActivityLog) ToResponse() ActivityLogResponse {
+	return ActivityLogResponse{
+		ID:        a.ID.String(),
+		Action:    a.Action,
+		PostID:    a.PostID.String(),
+		PostTitle: a.PostTitle,
+		Actor:     a.Actor,
+		ClientIP:  a.ClientIP,
+		UserAgent: a.UserAgent,
+		RequestID: a.RequestID,
+		Changes:   a.Changes,
+		LoggedAt:  a.LoggedAt,
+	}
+}
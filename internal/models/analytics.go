@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+type AnalyticsQuery struct {
+	From  time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To    time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit int       `form:"limit"`
+}
+
+type DailyPostActivity struct {
+	Day     time.Time `json:"day"`
+	Created int64     `json:"created"`
+	Updated int64     `json:"updated"`
+	Deleted int64     `json:"deleted"`
+}
+
+type PostRanking struct {
+	PostID string `json:"post_id"`
+	Title  string `json:"title"`
+	Count  int64  `json:"count"`
+	Rank   int64  `json:"rank"`
+}
+
+type TagTrend struct {
+	Day   time.Time `json:"day"`
+	Tag   string    `json:"tag"`
+	Views int64     `json:"views"`
+	// Change is the difference from the tag's previous day with views.
+	Change int64 `json:"change"`
+	Rank   int64 `json:"rank"`
+}
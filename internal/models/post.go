@@ -14,10 +14,22 @@ type Post struct {
 	Title     string         `json:"title" gorm:"type:varchar(255);not null" db:"title"`
 	Content   string         `json:"content" gorm:"type:text;not null" db:"content"`
 	Tags      pq.StringArray `json:"tags" gorm:"type:text[];default:'{}'" db:"tags"`
+	ViewCount int64          `json:"view_count" gorm:"not null;default:0" db:"view_count"`
+	Version   int64          `json:"version" gorm:"not null;default:1" db:"version"`
 	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime" db:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime" db:"updated_at"`
 
-	ActivityLogs []ActivityLog `json:"activity_logs,omitempty" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+	// ContentFormat is how Content is written: "markdown", "html" or
+	// "plain". ContentHTML is Content rendered and sanitized on write, and
+	// the fields after it are derived from ContentHTML.
+	ContentFormat      string          `json:"content_format" gorm:"type:varchar(16);not null;default:'markdown'" db:"content_format"`
+	ContentHTML        string          `json:"content_html" gorm:"type:text;not null;default:''" db:"content_html"`
+	Excerpt            string          `json:"excerpt" gorm:"type:text;not null;default:''" db:"excerpt"`
+	WordCount          int             `json:"word_count" gorm:"not null;default:0" db:"word_count"`
+	ReadingTimeMinutes int             `json:"reading_time_minutes" gorm:"not null;default:0" db:"reading_time_minutes"`
+	TOC                TableOfContents `json:"toc" gorm:"type:jsonb" db:"toc"`
+
+	ActivityLogs []ActivityLog `json:"activity_logs,omitempty" gorm:"foreignKey:PostID;constraint:-"`
 }
 
 func (Post) TableName() string {
@@ -37,9 +49,15 @@ func (p *Post) AfterAutoMigrate(tx *gorm.DB) error {
 	return nil
 }
 
+const (
+	SortRecent  = "recent"
+	SortPopular = "popular"
+)
+
 type PostSearchRequest struct {
 	Query string `json:"query" form:"q"`
 	Tags  string `json:"tags" form:"tags"`
+	Sort  string `json:"sort" form:"sort" binding:"omitempty,oneof=recent popular"`
 	Limit int    `json:"limit" form:"limit"`
 	Page  int    `json:"page" form:"page"`
 }
@@ -48,21 +66,50 @@ type PostCreateRequest struct {
 	Title   string   `json:"title" binding:"required"`
 	Content string   `json:"content" binding:"required"`
 	Tags    []string `json:"tags"`
+	// ContentFormat defaults to markdown.
+	ContentFormat string `json:"content_format" binding:"omitempty,oneof=markdown html plain"`
 }
 
 type PostUpdateRequest struct {
-	Title   *string  `json:"title"`
-	Content *string  `json:"content"`
-	Tags    []string `json:"tags"`
+	Title         *string  `json:"title"`
+	Content       *string  `json:"content"`
+	Tags          []string `json:"tags"`
+	ContentFormat *string  `json:"content_format" binding:"omitempty,oneof=markdown html plain"`
+}
+
+// PostDocument is the part of a post that PATCH requests operate on.
+type PostDocument struct {
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	ContentFormat string   `json:"content_format"`
+	Tags          []string `json:"tags"`
+}
+
+// PostPatchDocument is a PostDocument as patched. Patches may also set
+// AddTags and RemoveTags to edit the tags by value, without knowing their
+// positions; removals apply after additions.
+type PostPatchDocument struct {
+	PostDocument
+	AddTags    []string `json:"add_tags"`
+	RemoveTags []string `json:"remove_tags"`
 }
 
 type PostResponse struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Tags      []string  `json:"tags"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format"`
+	ContentHTML   string    `json:"content_html,omitempty"`
+	Tags          []string  `json:"tags"`
+	ViewCount     int64     `json:"view_count"`
+	Version       int64     `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Excerpt            string     `json:"excerpt"`
+	WordCount          int        `json:"word_count"`
+	ReadingTimeMinutes int        `json:"reading_time_minutes"`
+	TOC                []TOCEntry `json:"toc"`
 }
 
 type PostSearchResponse struct {
@@ -73,24 +120,55 @@ type PostSearchResponse struct {
 }
 
 func (p *Post) ToResponse() PostResponse {
+	toc := []TOCEntry(p.TOC)
+	if toc == nil {
+		toc = []TOCEntry{}
+	}
+	tags := []string(p.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
 	return PostResponse{
-		ID:        p.ID.String(),
-		Title:     p.Title,
-		Content:   p.Content,
-		Tags:      []string(p.Tags),
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
+		ID:            p.ID.String(),
+		Title:         p.Title,
+		Content:       p.Content,
+		ContentFormat: p.ContentFormat,
+		Tags:          tags,
+		ViewCount:     p.ViewCount,
+		Version:       p.Version,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+
+		Excerpt:            p.Excerpt,
+		WordCount:          p.WordCount,
+		ReadingTimeMinutes: p.ReadingTimeMinutes,
+		TOC:                toc,
+	}
+}
+
+func (p *Post) ToDocument() PostDocument {
+	tags := []string(p.Tags)
+	if tags == nil {
+		tags = []string{}
 	}
+	return PostDocument{Title: p.Title, Content: p.Content, ContentFormat: p.ContentFormat, Tags: tags}
 }
 
 func (p *Post) ToElasticsearchDoc() map[string]interface{} {
 	return map[string]interface{}{
-		"id":         p.ID.String(),
-		"title":      p.Title,
-		"content":    p.Content,
-		"tags":       []string(p.Tags),
-		"created_at": p.CreatedAt,
-		"updated_at": p.UpdatedAt,
+		"id":                   p.ID.String(),
+		"title":                p.Title,
+		"content":              p.Content,
+		"content_format":       p.ContentFormat,
+		"excerpt":              p.Excerpt,
+		"word_count":           p.WordCount,
+		"reading_time_minutes": p.ReadingTimeMinutes,
+		"toc":                  p.TOC,
+		"tags":                 []string(p.Tags),
+		"view_count":           p.ViewCount,
+		"version":              p.Version,
+		"created_at":           p.CreatedAt,
+		"updated_at":           p.UpdatedAt,
 	}
 }
 
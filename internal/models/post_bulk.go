@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkOperation is one line of a bulk request. ID, CreatedAt and UpdatedAt
+// are optional for creates, which then keep them, so migrations can
+// preserve post IDs and dates. A non-zero Version makes an update or
+// delete conditional on it, like If-Match.
+type BulkOperation struct {
+	Op            string     `json:"op"`
+	ID            string     `json:"id"`
+	Version       int64      `json:"version"`
+	Title         *string    `json:"title"`
+	Content       *string    `json:"content"`
+	ContentFormat *string    `json:"content_format"`
+	Tags          []string   `json:"tags"`
+	CreatedAt     *time.Time `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type BulkItemResult struct {
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Status  int    `json:"status"`
+	Version int64  `json:"version,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Atomic bool             `json:"atomic"`
+	Errors bool             `json:"errors"`
+	Items  []BulkItemResult `json:"items"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PostEvent is a post lifecycle change as streamed to SSE clients. ID is
+// the Redis stream entry ID, which clients send back as Last-Event-ID.
+// Post is the post as GET /api/v1/posts/:id returns it, or a DeletedPost
+// for deletions.
+type PostEvent struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Post       json.RawMessage `json:"post"`
+}
+
+// DeletedPost identifies the post of a delete_post event.
+type DeletedPost struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostStats holds daily view aggregates flushed from the Redis counters.
+type PostStats struct {
+	PostID         uuid.UUID `json:"post_id" gorm:"type:uuid;primaryKey" db:"post_id"`
+	Day            time.Time `json:"day" gorm:"type:date;primaryKey;index:,sort:desc" db:"day"`
+	Views          int64     `json:"views" gorm:"not null;default:0" db:"views"`
+	UniqueVisitors int64     `json:"unique_visitors" gorm:"not null;default:0" db:"unique_visitors"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime" db:"updated_at"`
+
+	Post Post `json:"-" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"`
+}
+
+func (PostStats) TableName() string {
+	return "post_stats"
+}
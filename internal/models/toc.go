@@ -0,0 +1,45 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// TOCEntry is a heading of a post. ID is the anchor of the heading in the
+// post's rendered HTML.
+type TOCEntry struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// TableOfContents is stored as JSONB. A nil table is stored as an empty
+// array, so NULL only marks posts whose summary has not been computed.
+type TableOfContents []TOCEntry
+
+func (t TableOfContents) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *TableOfContents) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for TableOfContents: %T", value)
+	}
+
+	return json.Unmarshal(data, t)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Webhook struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()" db:"id"`
+	URL       string         `json:"url" gorm:"type:text;not null" db:"url"`
+	Secret    string         `json:"-" gorm:"type:varchar(128);not null" db:"secret"`
+	Events    pq.StringArray `json:"events" gorm:"type:text[];not null;default:'{}'" db:"events"`
+	Active    bool           `json:"active" gorm:"not null;default:true" db:"active"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime" db:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()" db:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id" gorm:"type:uuid;not null;index" db:"webhook_id"`
+	Event          string          `json:"event" gorm:"type:varchar(50);not null" db:"event"`
+	PostID         uuid.UUID       `json:"post_id" gorm:"type:uuid;not null" db:"post_id"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:jsonb;not null" db:"payload"`
+	Status         string          `json:"status" gorm:"type:varchar(20);not null;index:idx_webhook_deliveries_due,priority:1" db:"status"`
+	Attempts       int             `json:"attempts" gorm:"not null;default:0" db:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" gorm:"not null;index:idx_webhook_deliveries_due,priority:2" db:"next_attempt_at"`
+	ResponseStatus int             `json:"response_status,omitempty" db:"response_status"`
+	LastError      string          `json:"last_error,omitempty" gorm:"type:text" db:"last_error"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime;index:,sort:desc" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" gorm:"autoUpdateTime" db:"updated_at"`
+
+	Webhook Webhook `json:"-" gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+type WebhookCreateRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=new_post update_post delete_post"`
+	Secret string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+type WebhookDeliveryQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit"`
+}
+
+// WebhookCreateResponse is the only response that carries the secret.
+type WebhookCreateResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers.
+type WebhookPayload struct {
+	ID         string       `json:"id"`
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Post       PostResponse `json:"post"`
+}
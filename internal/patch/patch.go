@@ -0,0 +1,322 @@
+// Package patch applies JSON Merge Patch (RFC 7386) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalid    = errors.New("invalid patch")
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// Patch transforms a JSON document.
+type Patch interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// MergePatch is an RFC 7386 merge patch: object members replace those of
+// the target, and null members remove them.
+type MergePatch struct {
+	patch interface{}
+}
+
+func DecodeMergePatch(data []byte) (*MergePatch, error) {
+	var p interface{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return &MergePatch{patch: p}, nil
+}
+
+func (p *MergePatch) Apply(doc []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return json.Marshal(merge(target, p.patch))
+}
+
+func merge(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = make(map[string]interface{})
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = merge(result[name], value)
+	}
+	return result
+}
+
+// Operation is a single RFC 6902 operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 patch. Operations are applied in order and the
+// patch fails as a whole if any of them does.
+type JSONPatch []Operation
+
+func DecodeJSONPatch(data []byte) (JSONPatch, error) {
+	var ops JSONPatch
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	for i, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d: %s requires a value", ErrInvalid, i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalid, i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalid, i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalid, i, err)
+		}
+	}
+	return ops, nil
+}
+
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	for i, op := range p {
+		var err error
+		if root, err = op.apply(root); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root interface{}) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+	from, _ := parsePointer(op.From)
+
+	switch op.Op {
+	case "add":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "remove":
+		root, _, err := remove(root, path)
+		return root, err
+	case "replace":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		root, _, err = remove(root, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "move":
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalid)
+		}
+		root, value, err := remove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "copy":
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		// Round-trip so the copy does not share maps or slices with the
+		// original.
+		data, _ := json.Marshal(value)
+		value, _ = decodeValue(data)
+		return add(root, path, value)
+	case "test":
+		want, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		got, err := get(root, path)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+}
+
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrInvalid, token)
+			}
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%w: cannot traverse %q", ErrInvalid, token)
+		}
+	}
+	return node, nil
+}
+
+// update calls fn with the container holding the last token of path and
+// stores the container fn returns back into its parent, since appending to
+// or removing from an array yields a new slice.
+func update(node interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q not found", ErrInvalid, path[0])
+		}
+		child, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = child
+		return n, nil
+	case []interface{}:
+		i, err := arrayIndex(path[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := update(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	}
+	return nil, fmt.Errorf("%w: cannot traverse %q", ErrInvalid, path[0])
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			if token == "-" {
+				return append(c, value), nil
+			}
+			i, err := arrayIndex(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[i+1:], c[i:])
+			c[i] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("%w: cannot add %q to a scalar", ErrInvalid, token)
+	})
+}
+
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalid)
+	}
+	var removed interface{}
+	root, err := update(root, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			value, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrInvalid, token)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[i]
+			return append(c[:i], c[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: cannot remove %q from a scalar", ErrInvalid, token)
+	})
+	return root, removed, err
+}
+
+// arrayIndex parses an array index token no greater than max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalid, token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrInvalid, i)
+	}
+	return i, nil
+}
@@ -0,0 +1,106 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"blog/internal/patch"
+)
+
+const doc = `{"title":"Hello","content":"World","tags":["go","web"]}`
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	json.Unmarshal([]byte(want), &w)
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"title":"Bye"}`, `{"title":"Bye","content":"World","tags":["go","web"]}`},
+		{"remove member", `{"tags":null}`, `{"title":"Hello","content":"World"}`},
+		{"replace array", `{"tags":["rust"]}`, `{"title":"Hello","content":"World","tags":["rust"]}`},
+		{"non-object patch", `[]`, `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := patch.DecodeMergePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got, err := p.Apply([]byte(doc))
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"append tag", `[{"op":"add","path":"/tags/-","value":"db"}]`, `{"title":"Hello","content":"World","tags":["go","web","db"]}`},
+		{"insert tag", `[{"op":"add","path":"/tags/0","value":"db"}]`, `{"title":"Hello","content":"World","tags":["db","go","web"]}`},
+		{"remove tag", `[{"op":"test","path":"/tags/0","value":"go"},{"op":"remove","path":"/tags/0"}]`, `{"title":"Hello","content":"World","tags":["web"]}`},
+		{"replace title", `[{"op":"replace","path":"/title","value":"Bye"}]`, `{"title":"Bye","content":"World","tags":["go","web"]}`},
+		{"move", `[{"op":"move","from":"/tags/1","path":"/tags/0"}]`, `{"title":"Hello","content":"World","tags":["web","go"]}`},
+		{"copy", `[{"op":"copy","from":"/title","path":"/content"}]`, `{"title":"Hello","content":"Hello","tags":["go","web"]}`},
+		{"escaped pointer", `[{"op":"add","path":"/a~1b","value":1}]`, `{"title":"Hello","content":"World","tags":["go","web"],"a/b":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := patch.DecodeJSONPatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			got, err := p.Apply([]byte(doc))
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"unknown op", `[{"op":"append","path":"/tags"}]`, patch.ErrInvalid},
+		{"missing value", `[{"op":"add","path":"/tags/-"}]`, patch.ErrInvalid},
+		{"bad pointer", `[{"op":"remove","path":"tags"}]`, patch.ErrInvalid},
+		{"index out of range", `[{"op":"remove","path":"/tags/2"}]`, patch.ErrInvalid},
+		{"missing member", `[{"op":"replace","path":"/summary","value":"x"}]`, patch.ErrInvalid},
+		{"failed test", `[{"op":"test","path":"/title","value":"Bye"}]`, patch.ErrTestFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := patch.DecodeJSONPatch([]byte(tt.patch))
+			if err == nil {
+				_, err = p.Apply([]byte(doc))
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
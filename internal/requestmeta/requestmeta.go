@@ -0,0 +1,23 @@
+// Package requestmeta carries metadata about the HTTP request being served
+// through its context, for the layers that log or record it.
+package requestmeta
+
+import "context"
+
+type contextKey struct{}
+
+// Meta describes the HTTP request a call is made on behalf of.
+type Meta struct {
+	ClientIP  string
+	UserAgent string
+	RequestID string
+}
+
+func With(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(contextKey{}).(Meta)
+	return meta
+}
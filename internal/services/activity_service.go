@@ -2,26 +2,153 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"strings"
+	"time"
 
+	"blog/internal/database"
 	"blog/internal/models"
+	"blog/internal/requestmeta"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
-type ActivityService struct{}
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+var ErrInvalidActivityQuery = newError(ErrValidation, "invalid_activity_query", "invalid activity query")
 
-func NewActivityService() *ActivityService {
-	return &ActivityService{}
+type ActivityService struct {
+	logs *database.ActivityLogRepository
 }
 
-func (s *ActivityService) LogActivity(ctx context.Context, tx *gorm.DB, action string, postID uuid.UUID) error {
-	log := models.NewActivityLog(action, postID)
+func NewActivityService(logs *database.ActivityLogRepository) *ActivityService {
+	return &ActivityService{logs: logs}
+}
+
+// LogActivity writes the audit entry in the unit of work bound to ctx so it
+// commits or rolls back together with the change it records. The post
+// title is copied into the entry so it stays readable after the post is
+// deleted. changes may be nil.
+func (s *ActivityService) LogActivity(ctx context.Context, action string, post *models.Post, changes models.FieldChanges) error {
+	meta := requestmeta.FromContext(ctx)
+
+	log := models.NewActivityLog(action, post.ID)
+	log.PostTitle = post.Title
+	log.Actor = ActorFromContext(ctx)
+	log.ClientIP = meta.ClientIP
+	log.UserAgent = meta.UserAgent
+	log.RequestID = meta.RequestID
+	log.Changes = changes
 
-	if err := tx.WithContext(ctx).Create(&log).Error; err != nil {
+	if err := s.logs.Create(ctx, log); err != nil {
 		return fmt.Errorf("failed to log activity: %w", err)
 	}
 
 	return nil
 }
+
+// HandlePostEvent logs the event inside the post's transaction. Deletions
+// keep the removed content in the entry's changes.
+func (s *ActivityService) HandlePostEvent(ctx context.Context, event PostEvent) error {
+	var changes models.FieldChanges
+	switch e := event.(type) {
+	case PostUpdated:
+		changes = e.Changes
+	case PostDeleted:
+		changes = models.FieldChanges{
+			"title":   {Old: e.Post.Title, New: nil},
+			"content": {Old: e.Post.Content, New: nil},
+			"tags":    {Old: []string(e.Post.Tags), New: nil},
+		}
+	}
+
+	return s.LogActivity(ctx, event.Action(), event.Subject(), changes)
+}
+
+// ListActivity returns logs newest first. Pages are keyed on
+// (logged_at, id) so rows inserted while paging do not shift later pages.
+func (s *ActivityService) ListActivity(ctx context.Context, query *models.ActivityLogQuery) (*models.ActivityLogListResponse, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+
+	filter := database.ActivityLogFilter{
+		Action: query.Action,
+		Actor:  query.Actor,
+		From:   query.From,
+		To:     query.To,
+		Limit:  limit + 1,
+	}
+	if query.PostID != "" {
+		postID, err := uuid.Parse(query.PostID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid post_id", ErrInvalidActivityQuery)
+		}
+		filter.PostID = postID
+	}
+	if query.Cursor != "" {
+		loggedAt, id, err := decodeActivityCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.BeforeTime, filter.BeforeID = loggedAt, id
+	}
+
+	logs, err := s.logs.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+
+	resp := &models.ActivityLogListResponse{}
+	if len(logs) > limit {
+		logs = logs[:limit]
+		last := logs[limit-1]
+		resp.NextCursor = encodeActivityCursor(last.LoggedAt, last.ID)
+	}
+
+	resp.Logs = make([]models.ActivityLogResponse, len(logs))
+	for i := range logs {
+		resp.Logs[i] = logs[i].ToResponse()
+	}
+
+	return resp, nil
+}
+
+func encodeActivityCursor(loggedAt time.Time, id uuid.UUID) string {
+	raw := loggedAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+var errInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidActivityQuery)
+
+func decodeActivityCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	loggedAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errInvalidCursor
+	}
+
+	return loggedAt, id, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"blog/internal/database"
+	"blog/internal/models"
+)
+
+const (
+	analyticsCacheKeyPrefix = "analytics:"
+	analyticsCacheTTL       = 5 * time.Minute
+	defaultAnalyticsWindow  = 30 * 24 * time.Hour
+	maxAnalyticsWindow      = 366 * 24 * time.Hour
+	defaultAnalyticsLimit   = 10
+	maxAnalyticsLimit       = 100
+)
+
+var ErrInvalidAnalyticsQuery = newError(ErrValidation, "invalid_analytics_query", "invalid analytics query")
+
+type AnalyticsService struct {
+	analytics *database.AnalyticsRepository
+	cache     *CacheService
+}
+
+func NewAnalyticsService(analytics *database.AnalyticsRepository, cache *CacheService) *AnalyticsService {
+	return &AnalyticsService{analytics: analytics, cache: cache}
+}
+
+func (s *AnalyticsService) DailyPostActivity(ctx context.Context, query *models.AnalyticsQuery) ([]models.DailyPostActivity, error) {
+	if err := normalizeAnalyticsQuery(query); err != nil {
+		return nil, err
+	}
+
+	var result []models.DailyPostActivity
+	err := s.cached(ctx, "daily", query, &result, func() error {
+		var err error
+		result, err = s.analytics.DailyPostActivity(ctx, query.From, query.To)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate post activity: %w", err)
+	}
+
+	return result, nil
+}
+
+// MostEditedPosts ranks posts by update count. The title comes from the
+// latest log entry so deleted posts are still labelled.
+func (s *AnalyticsService) MostEditedPosts(ctx context.Context, query *models.AnalyticsQuery) ([]models.PostRanking, error) {
+	if err := normalizeAnalyticsQuery(query); err != nil {
+		return nil, err
+	}
+
+	var result []models.PostRanking
+	err := s.cached(ctx, "most-edited", query, &result, func() error {
+		var err error
+		result, err = s.analytics.MostEditedPosts(ctx, query.From, query.To, query.Limit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank edited posts: %w", err)
+	}
+
+	return result, nil
+}
+
+// MostViewedPosts ranks posts by views recorded in post_stats, which has
+// daily granularity: the window is widened to whole days.
+func (s *AnalyticsService) MostViewedPosts(ctx context.Context, query *models.AnalyticsQuery) ([]models.PostRanking, error) {
+	if err := normalizeAnalyticsQuery(query); err != nil {
+		return nil, err
+	}
+
+	var result []models.PostRanking
+	err := s.cached(ctx, "most-viewed", query, &result, func() error {
+		var err error
+		result, err = s.analytics.MostViewedPosts(ctx, query.From, query.To, query.Limit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank viewed posts: %w", err)
+	}
+
+	return result, nil
+}
+
+// TagTrends returns, for each day in the window, the top tags by views of
+// the posts carrying them.
+func (s *AnalyticsService) TagTrends(ctx context.Context, query *models.AnalyticsQuery) ([]models.TagTrend, error) {
+	if err := normalizeAnalyticsQuery(query); err != nil {
+		return nil, err
+	}
+
+	var result []models.TagTrend
+	err := s.cached(ctx, "tag-trends", query, &result, func() error {
+		var err error
+		result, err = s.analytics.TagTrends(ctx, query.From, query.To, query.Limit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute tag trends: %w", err)
+	}
+
+	return result, nil
+}
+
+// cached serves dest from Redis when possible and otherwise fills it with
+// compute and stores the result. Cache failures never fail the request.
+func (s *AnalyticsService) cached(ctx context.Context, name string, query *models.AnalyticsQuery, dest interface{}, compute func() error) error {
+	key := fmt.Sprintf("%s%s:%d:%d:%d", analyticsCacheKeyPrefix, name, query.From.Unix(), query.To.Unix(), query.Limit)
+
+	if err := s.cache.GetJSON(ctx, key, dest); err == nil {
+		return nil
+	}
+
+	if err := compute(); err != nil {
+		return err
+	}
+
+	if err := s.cache.SetJSON(ctx, key, dest, analyticsCacheTTL); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+		log.Printf("[WARN] Failed to cache analytics: %v", err)
+	}
+
+	return nil
+}
+
+// normalizeAnalyticsQuery applies defaults and bounds. The default window
+// ends at the end of the current UTC day, the granularity of the reports, so
+// repeated dashboard loads share cache entries for the whole day.
+func normalizeAnalyticsQuery(query *models.AnalyticsQuery) error {
+	if query.To.IsZero() {
+		now := time.Now().UTC()
+		query.To = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultAnalyticsWindow)
+	}
+	if !query.From.Before(query.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidAnalyticsQuery)
+	}
+	if query.To.Sub(query.From) > maxAnalyticsWindow {
+		return fmt.Errorf("%w: window exceeds %d days", ErrInvalidAnalyticsQuery, int(maxAnalyticsWindow.Hours()/24))
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultAnalyticsLimit
+	}
+	if query.Limit > maxAnalyticsLimit {
+		query.Limit = maxAnalyticsLimit
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	bulkRetryBaseBackoff = time.Second
+	bulkRetryMaxBackoff  = time.Minute
+)
+
+// BulkIndexer is a PostIndex that buffers index and delete requests and
+// sends them to the underlying index in bulk: once flushSize posts are
+// pending, every flush interval, and on shutdown. Only the latest change to
+// each post is sent. Searches go straight to the underlying index and do
+// not see buffered writes.
+//
+// Failed flushes are retried with exponential backoff, for the documents
+// the index may still accept. At most maxPending posts are buffered; beyond
+// that the oldest changes are dropped and the index needs a reindex.
+type BulkIndexer struct {
+	index      PostIndex
+	flushSize  int
+	maxPending int
+	full       chan struct{}
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]*models.Post // nil means delete
+	order   []uuid.UUID
+	// inflight holds the posts being flushed and views the view counts
+	// reported for them meanwhile.
+	inflight map[uuid.UUID]bool
+	views    map[uuid.UUID]int64
+	failures int
+	retryAt  time.Time
+}
+
+func NewBulkIndexer(index PostIndex, flushSize, maxPending int) *BulkIndexer {
+	if maxPending < flushSize {
+		maxPending = flushSize
+	}
+	return &BulkIndexer{
+		index:      index,
+		flushSize:  flushSize,
+		maxPending: maxPending,
+		full:       make(chan struct{}, 1),
+		pending:    make(map[uuid.UUID]*models.Post),
+		inflight:   make(map[uuid.UUID]bool),
+		views:      make(map[uuid.UUID]int64),
+	}
+}
+
+func (b *BulkIndexer) IndexPost(ctx context.Context, post *models.Post) error {
+	b.add(post.ID, post)
+	return nil
+}
+
+func (b *BulkIndexer) DeletePost(ctx context.Context, id uuid.UUID) error {
+	b.add(id, nil)
+	return nil
+}
+
+func (b *BulkIndexer) BulkIndex(ctx context.Context, upserts []*models.Post, deletes []uuid.UUID) error {
+	for _, post := range upserts {
+		b.add(post.ID, post)
+	}
+	for _, id := range deletes {
+		b.add(id, nil)
+	}
+	return nil
+}
+
+// UpdateViewCount sets the view count of a buffered post in the buffer, so
+// the flush neither overwrites the new count nor races the post's creation.
+// Counts for posts not in the buffer go straight to the index.
+func (b *BulkIndexer) UpdateViewCount(ctx context.Context, id uuid.UUID, viewCount int64) error {
+	b.mu.Lock()
+	if post, ok := b.pending[id]; ok {
+		if post != nil {
+			b.pending[id] = withViewCount(post, viewCount)
+		}
+		b.mu.Unlock()
+		return nil
+	}
+	if b.inflight[id] {
+		b.views[id] = viewCount
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	return b.index.UpdateViewCount(ctx, id, viewCount)
+}
+
+func withViewCount(post *models.Post, viewCount int64) *models.Post {
+	updated := *post
+	updated.ViewCount = viewCount
+	return &updated
+}
+
+func (b *BulkIndexer) SearchPosts(ctx context.Context, req *models.PostSearchRequest) (*models.PostSearchResponse, error) {
+	return b.index.SearchPosts(ctx, req)
+}
+
+func (b *BulkIndexer) add(id uuid.UUID, post *models.Post) {
+	b.mu.Lock()
+	b.put(id, post)
+	n := len(b.order)
+	b.mu.Unlock()
+
+	pendingIndexDocs.Set(int64(n))
+	if n >= b.flushSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// put buffers the change to the post, dropping the oldest buffered change
+// if the buffer is full. b.mu must be held.
+func (b *BulkIndexer) put(id uuid.UUID, post *models.Post) {
+	if _, ok := b.pending[id]; !ok {
+		if len(b.order) >= b.maxPending {
+			oldest := b.order[0]
+			b.order = b.order[1:]
+			delete(b.pending, oldest)
+			searchIndexing.Add("dropped", 1)
+			log.Printf("[WARN] Search index buffer full, dropped pending change to post %s", oldest)
+		}
+		b.order = append(b.order, id)
+	}
+	b.pending[id] = post
+}
+
+// Pending is the number of posts waiting to be sent.
+func (b *BulkIndexer) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.order)
+}
+
+func (b *BulkIndexer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.full:
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := b.Flush(flushCtx); err != nil {
+				log.Printf("[WARN] Failed to flush %d posts to search index on shutdown: %v", b.Pending(), err)
+			}
+			cancel()
+			return
+		}
+		if b.backingOff() {
+			continue
+		}
+		if err := b.Flush(ctx); err != nil {
+			log.Printf("[WARN] Failed to flush posts to search index: %v", err)
+		}
+	}
+}
+
+func (b *BulkIndexer) backingOff() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.retryAt)
+}
+
+// Flush sends everything pending in one bulk request. Posts the index may
+// still accept are put back on failure, unless they changed again in the
+// meantime, and further flushes from Run back off until the index recovers.
+func (b *BulkIndexer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	pending, order := b.pending, b.order
+	b.pending, b.order = make(map[uuid.UUID]*models.Post), nil
+	for _, id := range order {
+		b.inflight[id] = true
+	}
+	b.mu.Unlock()
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	var upserts []*models.Post
+	var deletes []uuid.UUID
+	for _, id := range order {
+		if post := pending[id]; post != nil {
+			upserts = append(upserts, post)
+		} else {
+			deletes = append(deletes, id)
+		}
+	}
+
+	err := b.index.BulkIndex(ctx, upserts, deletes)
+
+	retry := order
+	var bulkErr *BulkIndexError
+	if errors.As(err, &bulkErr) {
+		retry = nil
+		for _, failed := range bulkErr.Failed {
+			if failed.Retryable() {
+				retry = append(retry, failed.ID)
+			} else {
+				log.Printf("[WARN] Search index rejected post %s with status %d, not retrying", failed.ID, failed.Status)
+			}
+		}
+	}
+
+	b.mu.Lock()
+	if err != nil {
+		for _, id := range retry {
+			if _, newer := b.pending[id]; !newer {
+				b.put(id, pending[id])
+			}
+		}
+	}
+	if len(retry) > 0 && err != nil {
+		b.failures++
+		b.retryAt = time.Now().Add(bulkRetryBackoff(b.failures))
+	} else {
+		b.failures = 0
+		b.retryAt = time.Time{}
+	}
+
+	// View counts reported during the flush go to the buffered copy if the
+	// post is pending again and to the index otherwise.
+	views := make(map[uuid.UUID]int64)
+	for id, count := range b.views {
+		if post, ok := b.pending[id]; ok {
+			if post != nil {
+				b.pending[id] = withViewCount(post, count)
+			}
+		} else {
+			views[id] = count
+		}
+	}
+	b.views = make(map[uuid.UUID]int64)
+	b.inflight = make(map[uuid.UUID]bool)
+	b.mu.Unlock()
+
+	for id, count := range views {
+		if err := b.index.UpdateViewCount(ctx, id, count); err != nil {
+			log.Printf("[WARN] Failed to update view count in search index: %v", err)
+		}
+	}
+
+	pendingIndexDocs.Set(int64(b.Pending()))
+	return err
+}
+
+func bulkRetryBackoff(failures int) time.Duration {
+	backoff := bulkRetryBaseBackoff << (failures - 1)
+	if backoff <= 0 || backoff > bulkRetryMaxBackoff {
+		return bulkRetryMaxBackoff
+	}
+	return backoff
+}
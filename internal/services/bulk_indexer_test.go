@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"blog/internal/memory"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+)
+
+func indexed(t *testing.T, index *memory.SearchIndex) int64 {
+	t.Helper()
+
+	res, err := index.SearchPosts(context.Background(), &models.PostSearchRequest{})
+	if err != nil {
+		t.Fatalf("SearchPosts: %v", err)
+	}
+	return res.TotalCount
+}
+
+func TestBulkIndexerFlushesLatestChanges(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 100, 1000)
+
+	kept := &models.Post{ID: uuid.New(), Title: "Kept"}
+	gone := &models.Post{ID: uuid.New(), Title: "Gone"}
+	indexer.IndexPost(ctx, kept)
+	indexer.IndexPost(ctx, gone)
+	indexer.IndexPost(ctx, &models.Post{ID: kept.ID, Title: "Kept again"})
+	indexer.DeletePost(ctx, gone.ID)
+
+	if n := indexed(t, index); n != 0 {
+		t.Fatalf("%d posts indexed before flush", n)
+	}
+	if err := indexer.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := index.BulkRequests(); n != 1 {
+		t.Errorf("made %d bulk requests, want 1", n)
+	}
+	res, _ := index.SearchPosts(ctx, &models.PostSearchRequest{})
+	if res.TotalCount != 1 || res.Posts[0].Title != "Kept again" {
+		t.Errorf("index holds %+v, want only the latest version of the kept post", res.Posts)
+	}
+}
+
+func TestBulkIndexerRequeuesOnFailure(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 100, 1000)
+
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New(), Title: "One"})
+	index.Unavailable = true
+	if err := indexer.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded with the index down")
+	}
+	if n := indexer.Pending(); n != 1 {
+		t.Fatalf("%d posts pending after failed flush, want 1", n)
+	}
+
+	index.Unavailable = false
+	if err := indexer.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := indexed(t, index); n != 1 {
+		t.Errorf("%d posts indexed, want 1", n)
+	}
+}
+
+func TestBulkIndexerRetriesOnlyRetryableItems(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 100, 1000)
+
+	rejected, throttled := uuid.New(), uuid.New()
+	index.Reject = map[uuid.UUID]int{rejected: 400, throttled: 429}
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	indexer.IndexPost(ctx, &models.Post{ID: rejected})
+	indexer.IndexPost(ctx, &models.Post{ID: throttled})
+
+	if err := indexer.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded with rejected posts")
+	}
+	if n := indexer.Pending(); n != 1 {
+		t.Fatalf("%d posts pending, want only the throttled one", n)
+	}
+
+	index.Reject = nil
+	if err := indexer.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := indexed(t, index); n != 2 {
+		t.Errorf("%d posts indexed, want 2", n)
+	}
+}
+
+func TestBulkIndexerDropsOldestWhenFull(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 2, 2)
+
+	index.Unavailable = true
+	for i := 0; i < 3; i++ {
+		indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	}
+	indexer.Flush(ctx)
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	if n := indexer.Pending(); n != 2 {
+		t.Errorf("%d posts pending, want the cap of 2", n)
+	}
+}
+
+func TestBulkIndexerViewCountsUpdateBufferedPosts(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 100, 1000)
+
+	post := &models.Post{ID: uuid.New(), Title: "Viewed", ViewCount: 1}
+	indexer.IndexPost(ctx, post)
+	if err := indexer.UpdateViewCount(ctx, post.ID, 5); err != nil {
+		t.Fatalf("UpdateViewCount: %v", err)
+	}
+	if err := indexer.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	res, _ := index.SearchPosts(ctx, &models.PostSearchRequest{})
+	if res.TotalCount != 1 || res.Posts[0].ViewCount != 5 {
+		t.Errorf("index holds %+v, want the post with 5 views", res.Posts)
+	}
+	if post.ViewCount != 1 {
+		t.Errorf("caller's post was modified")
+	}
+}
+
+func TestBulkIndexerRun(t *testing.T) {
+	index := memory.NewSearchIndex()
+	indexer := services.NewBulkIndexer(index, 2, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		indexer.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	deadline := time.Now().Add(time.Second)
+	for index.BulkRequests() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := indexed(t, index); n != 2 {
+		t.Errorf("%d posts indexed once the buffer filled, want 2", n)
+	}
+
+	indexer.IndexPost(ctx, &models.Post{ID: uuid.New()})
+	cancel()
+	<-done
+	if n := indexed(t, index); n != 3 {
+		t.Errorf("%d posts indexed after shutdown, want 3", n)
+	}
+}
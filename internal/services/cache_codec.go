@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Cache entries are framed as [schema version][encoding][payload]. Bump
+// cacheSchemaVersion whenever cachedPost changes shape so entries written
+// by an older build are read as misses instead of decoding into the wrong
+// fields.
+const (
+	cacheSchemaVersion byte = 5
+
+	cacheEncodingGob     byte = 1
+	cacheEncodingGobGzip byte = 2
+
+	cacheCompressThreshold = 1024
+	cacheHeaderSize        = 2
+)
+
+var errCacheSchemaMismatch = errors.New("cache entry schema mismatch")
+
+type cachedPost struct {
+	ID            uuid.UUID
+	Title         string
+	Content       string
+	ContentFormat string
+	ContentHTML   string
+	Tags          []string
+	ViewCount     int64
+	Version       int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	Excerpt            string
+	WordCount          int
+	ReadingTimeMinutes int
+	TOC                []models.TOCEntry
+}
+
+func encodeCachedPost(post *models.Post) ([]byte, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(cachedPost{
+		ID:            post.ID,
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+		ContentHTML:   post.ContentHTML,
+		Tags:          post.Tags,
+		ViewCount:     post.ViewCount,
+		Version:       post.Version,
+		CreatedAt:     post.CreatedAt,
+		UpdatedAt:     post.UpdatedAt,
+
+		Excerpt:            post.Excerpt,
+		WordCount:          post.WordCount,
+		ReadingTimeMinutes: post.ReadingTimeMinutes,
+		TOC:                post.TOC,
+	}); err != nil {
+		return nil, err
+	}
+
+	encoding := cacheEncodingGob
+	if payload.Len() > cacheCompressThreshold {
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(payload.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		payload = compressed
+		encoding = cacheEncodingGobGzip
+	}
+
+	data := make([]byte, 0, cacheHeaderSize+payload.Len())
+	data = append(data, cacheSchemaVersion, encoding)
+	return append(data, payload.Bytes()...), nil
+}
+
+func decodeCachedPost(data []byte) (*models.Post, error) {
+	if len(data) < cacheHeaderSize || data[0] != cacheSchemaVersion {
+		return nil, errCacheSchemaMismatch
+	}
+
+	var r io.Reader = bytes.NewReader(data[cacheHeaderSize:])
+	switch data[1] {
+	case cacheEncodingGob:
+	case cacheEncodingGobGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w: unknown encoding %d", errCacheSchemaMismatch, data[1])
+	}
+
+	var cached cachedPost
+	if err := gob.NewDecoder(r).Decode(&cached); err != nil {
+		return nil, err
+	}
+	// gob drops empty slices, so a post without tags decodes with nil Tags.
+	if cached.Tags == nil {
+		cached.Tags = []string{}
+	}
+
+	return &models.Post{
+		ID:            cached.ID,
+		Title:         cached.Title,
+		Content:       cached.Content,
+		ContentFormat: cached.ContentFormat,
+		ContentHTML:   cached.ContentHTML,
+		Tags:          cached.Tags,
+		ViewCount:     cached.ViewCount,
+		Version:       cached.Version,
+		CreatedAt:     cached.CreatedAt,
+		UpdatedAt:     cached.UpdatedAt,
+
+		Excerpt:            cached.Excerpt,
+		WordCount:          cached.WordCount,
+		ReadingTimeMinutes: cached.ReadingTimeMinutes,
+		TOC:                cached.TOC,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestCachedPostRoundTripKeepsEmptyTags(t *testing.T) {
+	for name, content := range map[string]string{
+		"plain":      "short",
+		"compressed": strings.Repeat("long body ", cacheCompressThreshold),
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := encodeCachedPost(&models.Post{ID: uuid.New(), Title: "t", Content: content, Tags: pq.StringArray{}})
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			post, err := decodeCachedPost(data)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if post.Tags == nil {
+				t.Fatal("expected empty tags, got nil")
+			}
+
+			body, err := json.Marshal(post.ToResponse())
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if !strings.Contains(string(body), `"tags":[]`) {
+				t.Errorf("expected \"tags\":[] in %s", body)
+			}
+		})
+	}
+}
@@ -3,61 +3,340 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"blog/internal/database"
 	"blog/internal/models"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrCacheUnavailable = errors.New("cache unavailable")
+	ErrCacheMiss        = errors.New("cache miss")
+)
+
 type CacheService struct {
-	redis *database.RedisClient
+	redis   *database.RedisClient
+	breaker *circuitBreaker
 }
 
 func NewCacheService(redis *database.RedisClient) *CacheService {
-	return &CacheService{redis: redis}
+	s := &CacheService{redis: redis}
+	s.breaker = newCircuitBreaker(cacheFailureThreshold, func() { go s.reconnect() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheProbeTimeout)
+	defer cancel()
+	if err := s.redis.Ping(ctx).Err(); err != nil {
+		log.Printf("[WARN] Redis unavailable, serving without cache: %v", err)
+		s.breaker.Trip()
+	}
+
+	return s
 }
 
 const (
 	postCacheKeyPrefix = "post:"
 	postCacheTTL       = 5 * time.Minute
+
+	postViewsKeyPrefix    = "views:post:"
+	postVisitorsKeyPrefix = "visitors:post:"
+	postViewsDirtyKey     = "views:dirty"
+	postVisitorsTTL       = 48 * time.Hour
+	postViewDayLayout     = "2006-01-02"
+
+	cacheFailureThreshold  = 3
+	cacheReconnectInterval = 5 * time.Second
+	cacheProbeTimeout      = 2 * time.Second
+	cacheRecoverTimeout    = 30 * time.Second
 )
 
+// Available reports whether Redis calls are currently being attempted.
+func (s *CacheService) Available() bool {
+	return s.breaker.Allow()
+}
+
 func (s *CacheService) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
 	key := postCacheKeyPrefix + id.String()
 
-	result, err := s.redis.Get(ctx, key).Result()
+	var result []byte
+	err := s.call(func() error {
+		var err error
+		result, err = s.redis.Get(ctx, key).Bytes()
+		return err
+	})
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var post models.Post
-	if err := json.Unmarshal([]byte(result), &post); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cached post: %w", err)
+	post, err := decodeCachedPost(result)
+	if errors.Is(err, errCacheSchemaMismatch) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cached post: %w", err)
 	}
 
-	return &post, nil
+	return post, nil
 }
 
 func (s *CacheService) SetPost(ctx context.Context, post *models.Post) error {
 	key := postCacheKeyPrefix + post.ID.String()
 
-	data, err := json.Marshal(post)
+	data, err := encodeCachedPost(post)
 	if err != nil {
-		return fmt.Errorf("failed to marshal post: %w", err)
+		return fmt.Errorf("failed to encode post: %w", err)
 	}
 
-	return s.redis.Set(ctx, key, data, postCacheTTL).Err()
+	return s.call(func() error {
+		return s.redis.Set(ctx, key, data, postCacheTTL).Err()
+	})
 }
 
 func (s *CacheService) DeletePost(ctx context.Context, id uuid.UUID) error {
 	key := postCacheKeyPrefix + id.String()
-	return s.redis.Del(ctx, key).Err()
+	return s.call(func() error {
+		return s.redis.Del(ctx, key).Err()
+	})
 }
 
 func (s *CacheService) InvalidatePostCache(ctx context.Context, id uuid.UUID) error {
-	key := postCacheKeyPrefix + id.String()
-	return s.redis.Del(ctx, key).Err()
+	return s.DeletePost(ctx, id)
+}
+
+// GetJSON loads a JSON-encoded value stored with SetJSON into dest.
+func (s *CacheService) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	var result []byte
+	err := s.call(func() error {
+		var err error
+		result, err = s.redis.Get(ctx, key).Bytes()
+		return err
+	})
+	if err == redis.Nil {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(result, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal cached value: %w", err)
+	}
+	return nil
+}
+
+func (s *CacheService) SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return s.call(func() error {
+		return s.redis.Set(ctx, key, data, ttl).Err()
+	})
+}
+
+func (s *CacheService) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.call(func() error {
+		return s.redis.Del(ctx, keys...).Err()
+	})
+}
+
+// RecordPostView bumps the view counter for the post on the given day and,
+// when visitor is set, adds it to that day's HyperLogLog of unique visitors.
+func (s *CacheService) RecordPostView(ctx context.Context, id uuid.UUID, day time.Time, visitor string) error {
+	member := postViewMember(id, day)
+
+	return s.call(func() error {
+		_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Incr(ctx, postViewsKeyPrefix+member)
+			if visitor != "" {
+				pipe.PFAdd(ctx, postVisitorsKeyPrefix+member, visitor)
+				pipe.Expire(ctx, postVisitorsKeyPrefix+member, postVisitorsTTL)
+			}
+			pipe.SAdd(ctx, postViewsDirtyKey, member)
+			return nil
+		})
+		return err
+	})
+}
+
+// PopPostViews drains up to count pending per-day counters. The caller owns
+// the returned views and must hand them back with RestorePostViews if it
+// fails to persist them.
+func (s *CacheService) PopPostViews(ctx context.Context, count int64) ([]models.PostStats, error) {
+	var members []string
+	err := s.call(func() error {
+		var err error
+		members, err = s.redis.SPopN(ctx, postViewsDirtyKey, count).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.PostStats, 0, len(members))
+	for i, member := range members {
+		id, day, err := parsePostViewMember(member)
+		if err != nil {
+			log.Printf("[WARN] Dropping malformed view counter %q: %v", member, err)
+			continue
+		}
+
+		// The counter is deleted last so that a failure before it leaves
+		// the member's views in place.
+		var views, visitors int64
+		err = s.call(func() error {
+			var err error
+			visitors, err = s.redis.PFCount(ctx, postVisitorsKeyPrefix+member).Result()
+			if err != nil {
+				return err
+			}
+			views, err = s.redis.GetDel(ctx, postViewsKeyPrefix+member).Int64()
+			if err == redis.Nil {
+				views, err = 0, nil
+			}
+			return err
+		})
+		if err != nil {
+			s.requeuePostViews(ctx, stats, members[i:])
+			return nil, err
+		}
+
+		if views == 0 {
+			continue
+		}
+		stats = append(stats, models.PostStats{
+			PostID:         id,
+			Day:            day,
+			Views:          views,
+			UniqueVisitors: visitors,
+		})
+	}
+
+	return stats, nil
+}
+
+// RestorePostViews re-queues views that were popped but not persisted.
+func (s *CacheService) RestorePostViews(ctx context.Context, stats []models.PostStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	return s.call(func() error {
+		_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, st := range stats {
+				member := postViewMember(st.PostID, st.Day)
+				pipe.IncrBy(ctx, postViewsKeyPrefix+member, st.Views)
+				pipe.SAdd(ctx, postViewsDirtyKey, member)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// requeuePostViews hands back views popped before a failure together with
+// the members that were not read yet.
+func (s *CacheService) requeuePostViews(ctx context.Context, stats []models.PostStats, members []string) {
+	if err := s.RestorePostViews(ctx, stats); err != nil {
+		log.Printf("[WARN] Failed to restore %d popped view counters: %v", len(stats), err)
+	}
+	err := s.call(func() error {
+		return s.redis.SAdd(ctx, postViewsDirtyKey, members).Err()
+	})
+	if err != nil {
+		log.Printf("[WARN] Failed to re-queue %d view counters: %v", len(members), err)
+	}
+}
+
+func postViewMember(id uuid.UUID, day time.Time) string {
+	return id.String() + ":" + day.UTC().Format(postViewDayLayout)
+}
+
+func parsePostViewMember(member string) (uuid.UUID, time.Time, error) {
+	idStr, dayStr, ok := strings.Cut(member, ":")
+	if !ok {
+		return uuid.Nil, time.Time{}, fmt.Errorf("missing day")
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	day, err := time.Parse(postViewDayLayout, dayStr)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	return id, day, nil
+}
+
+// call runs fn through the circuit breaker. A cache miss is a healthy
+// response and does not count as a failure.
+func (s *CacheService) call(fn func() error) error {
+	if !s.breaker.Allow() {
+		return ErrCacheUnavailable
+	}
+
+	err := fn()
+	if err != nil && err != redis.Nil {
+		s.breaker.Failure()
+		return err
+	}
+
+	s.breaker.Success()
+	return err
+}
+
+// reconnect pings Redis until it answers, then purges cached posts, feeds
+// and analytics before closing the breaker: invalidations skipped during
+// the outage would otherwise leave stale entries behind.
+func (s *CacheService) reconnect() {
+	ticker := time.NewTicker(cacheReconnectInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.restore(); err != nil {
+			continue
+		}
+
+		s.breaker.Reset()
+		log.Println("Redis connection restored")
+		return
+	}
+}
+
+func (s *CacheService) restore() error {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheRecoverTimeout)
+	defer cancel()
+
+	if err := s.redis.Ping(ctx).Err(); err != nil {
+		return err
+	}
+
+	for _, prefix := range []string{postCacheKeyPrefix, feedCacheKeyPrefix, feedChangedKeyPrefix, analyticsCacheKeyPrefix} {
+		iter := s.redis.Scan(ctx, 0, prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			if err := s.redis.Del(ctx, iter.Val()).Err(); err != nil {
+				return err
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
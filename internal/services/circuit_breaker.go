@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sync"
+)
+
+// circuitBreaker trips after threshold consecutive failures. While open,
+// callers should skip the protected dependency; onOpen is invoked once per
+// trip so the owner can start probing for recovery and call reset.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  int
+	open      bool
+	onOpen    func()
+}
+
+func newCircuitBreaker(threshold int, onOpen func()) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		onOpen:    onOpen,
+	}
+}
+
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open
+}
+
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	b.failures++
+	trip := !b.open && b.failures >= b.threshold
+	if trip {
+		b.open = true
+	}
+	b.mu.Unlock()
+
+	if trip && b.onOpen != nil {
+		b.onOpen()
+	}
+}
+
+// Trip opens the breaker immediately, regardless of the failure count.
+func (b *circuitBreaker) Trip() {
+	b.mu.Lock()
+	trip := !b.open
+	b.open = true
+	b.failures = b.threshold
+	b.mu.Unlock()
+
+	if trip && b.onOpen != nil {
+		b.onOpen()
+	}
+}
+
+func (b *circuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open = false
+	b.failures = 0
+}
@@ -0,0 +1,16 @@
+package services
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor records the authenticated caller so activity logs written while
+// handling the request can be attributed to them.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,43 @@
+package services
+
+// Error kinds. Service errors wrap one of these so callers can tell how a
+// request failed without matching messages; the HTTP layer maps each kind
+// to a status code.
+var (
+	ErrNotFound   = newError(nil, "not_found", "not found")
+	ErrConflict   = newError(nil, "conflict", "conflict")
+	ErrValidation = newError(nil, "validation_failed", "validation failed")
+	ErrForbidden  = newError(nil, "forbidden", "forbidden")
+
+	ErrPreconditionFailed   = newError(nil, "precondition_failed", "precondition failed")
+	ErrPreconditionRequired = newError(nil, "precondition_required", "precondition required")
+)
+
+var (
+	ErrPostNotFound = newError(ErrNotFound, "post_not_found", "post not found")
+	ErrPostModified = newError(ErrPreconditionFailed, "post_modified", "post has been modified")
+
+	ErrInvalidContentFormat = newError(ErrValidation, "invalid_content_format", "invalid content format")
+
+	ErrInvalidPatch    = newError(ErrValidation, "invalid_patch", "invalid patch")
+	ErrPatchTestFailed = newError(ErrConflict, "patch_test_failed", "patch test failed")
+
+	ErrInvalidBulkOperation = newError(ErrValidation, "invalid_bulk_operation", "invalid bulk operation")
+	ErrBulkRolledBack       = newError(ErrConflict, "bulk_rolled_back", "rolled back because another operation failed")
+)
+
+// codedError is a sentinel with a stable, machine-readable code that
+// matches its kind with errors.Is.
+type codedError struct {
+	kind error
+	code string
+	msg  string
+}
+
+func newError(kind error, code, msg string) error {
+	return &codedError{kind: kind, code: code, msg: msg}
+}
+
+func (e *codedError) Error() string { return e.msg }
+func (e *codedError) Code() string  { return e.code }
+func (e *codedError) Unwrap() error { return e.kind }
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PostEvent is a post lifecycle change published on the EventBus.
+type PostEvent interface {
+	// Action is the activity log action for the event (models.Action*).
+	Action() string
+	Subject() *models.Post
+}
+
+type PostCreated struct {
+	Post *models.Post
+}
+
+type PostUpdated struct {
+	Post    *models.Post
+	Changes models.FieldChanges
+}
+
+// PostDeleted carries the post as it was just before deletion.
+type PostDeleted struct {
+	Post *models.Post
+}
+
+func (e PostCreated) Action() string        { return models.ActionCreatePost }
+func (e PostCreated) Subject() *models.Post { return e.Post }
+func (e PostUpdated) Action() string        { return models.ActionUpdatePost }
+func (e PostUpdated) Subject() *models.Post { return e.Post }
+func (e PostDeleted) Action() string        { return models.ActionDeletePost }
+func (e PostDeleted) Subject() *models.Post { return e.Post }
+
+// TxHandler runs inside the transaction that changes the post; ctx carries
+// the transaction and returning an error rolls the change back.
+type TxHandler func(ctx context.Context, event PostEvent) error
+
+// Handler runs after the change has committed. It cannot undo the change,
+// so it reports its own failures.
+type Handler func(ctx context.Context, event PostEvent)
+
+// BatchHandler is a Handler that receives all events committed together,
+// e.g. by a bulk request, so it can apply them in one round trip.
+type BatchHandler func(ctx context.Context, events []PostEvent)
+
+// EventBus decouples PostService from the side effects of a post change.
+// Handlers run synchronously, in subscription order, so by the time a
+// write returns the cache has been invalidated and the index updated.
+type EventBus struct {
+	mu            sync.RWMutex
+	txHandlers    []TxHandler
+	handlers      []Handler
+	batchHandlers []BatchHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (b *EventBus) SubscribeTx(h TxHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.txHandlers = append(b.txHandlers, h)
+}
+
+func (b *EventBus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *EventBus) SubscribeBatch(h BatchHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batchHandlers = append(b.batchHandlers, h)
+}
+
+// PublishTx must be called with the context of a UnitOfWork transaction.
+func (b *EventBus) PublishTx(ctx context.Context, event PostEvent) error {
+	b.mu.RLock()
+	handlers := b.txHandlers
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *EventBus) Publish(ctx context.Context, event PostEvent) {
+	b.PublishAll(ctx, []PostEvent{event})
+}
+
+// PublishAll runs the Handlers for each event in order and then every
+// BatchHandler once with all of them.
+func (b *EventBus) PublishAll(ctx context.Context, events []PostEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	batchHandlers := b.batchHandlers
+	b.mu.RUnlock()
+
+	for _, event := range events {
+		for _, h := range handlers {
+			h(ctx, event)
+		}
+	}
+	for _, h := range batchHandlers {
+		h(ctx, events)
+	}
+}
+
+// InvalidateCachedPosts drops the cached copy of updated and deleted posts.
+func InvalidateCachedPosts(cache PostCache) Handler {
+	return func(ctx context.Context, event PostEvent) {
+		switch event.(type) {
+		case PostUpdated, PostDeleted:
+			if err := cache.DeletePost(ctx, event.Subject().ID); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+				log.Printf("[WARN] Failed to invalidate cached post: %v", err)
+			}
+		}
+	}
+}
+
+// SyncSearchIndex mirrors post changes into the search index. A batch is
+// sent as one bulk request holding the final state of each post.
+func SyncSearchIndex(index PostIndex) BatchHandler {
+	return func(ctx context.Context, events []PostEvent) {
+		if len(events) == 1 {
+			post := events[0].Subject()
+
+			var err error
+			switch events[0].(type) {
+			case PostCreated, PostUpdated:
+				err = index.IndexPost(ctx, post)
+			case PostDeleted:
+				err = index.DeletePost(ctx, post.ID)
+			}
+			if err != nil {
+				log.Printf("[WARN] Failed to sync post %s to search index: %v", post.ID, err)
+			}
+			return
+		}
+
+		latest := make(map[uuid.UUID]PostEvent, len(events))
+		var order []uuid.UUID
+		for _, event := range events {
+			id := event.Subject().ID
+			if _, seen := latest[id]; !seen {
+				order = append(order, id)
+			}
+			latest[id] = event
+		}
+
+		var upserts []*models.Post
+		var deletes []uuid.UUID
+		for _, id := range order {
+			if _, ok := latest[id].(PostDeleted); ok {
+				deletes = append(deletes, id)
+			} else {
+				upserts = append(upserts, latest[id].Subject())
+			}
+		}
+		if err := index.BulkIndex(ctx, upserts, deletes); err != nil {
+			log.Printf("[WARN] Failed to sync %d posts to search index: %v", len(order), err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog/internal/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	postEventsStreamKey   = "events:posts:stream"
+	postEventsChannel     = "events:posts"
+	postEventsStreamLen   = 10000
+	postEventsReplayLimit = 1000
+	eventSubscriberBuffer = 64
+)
+
+// EventStreamService fans post events out to SSE subscribers. Every event
+// is appended to a capped Redis stream, for Last-Event-ID replay, and
+// published on a channel that each server instance relays to its local
+// subscribers.
+type EventStreamService struct {
+	cache *CacheService
+
+	mu          sync.Mutex
+	subscribers map[chan models.PostEvent]struct{}
+	closed      bool
+}
+
+func NewEventStreamService(cache *CacheService) *EventStreamService {
+	return &EventStreamService{
+		cache:       cache,
+		subscribers: make(map[chan models.PostEvent]struct{}),
+	}
+}
+
+func (s *EventStreamService) Publish(ctx context.Context, eventType string, post *models.Post) error {
+	payload, err := json.Marshal(postEventPayload(eventType, post))
+	if err != nil {
+		return err
+	}
+	event := models.PostEvent{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Post:       payload,
+	}
+
+	return s.cache.call(func() error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		id, err := s.cache.redis.XAdd(ctx, &redis.XAddArgs{
+			Stream: postEventsStreamKey,
+			MaxLen: postEventsStreamLen,
+			Approx: true,
+			Values: map[string]interface{}{"event": data},
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		event.ID = id
+		data, err = json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return s.cache.redis.Publish(ctx, postEventsChannel, data).Err()
+	})
+}
+
+// postEventPayload limits what the public stream carries: the fields the
+// public GET endpoint returns, and only the identity of a deleted post.
+func postEventPayload(eventType string, post *models.Post) interface{} {
+	if eventType == models.ActionDeletePost {
+		return models.DeletedPost{ID: post.ID.String(), Version: post.Version}
+	}
+	return post.ToResponse()
+}
+
+func (s *EventStreamService) HandlePostEvent(ctx context.Context, event PostEvent) {
+	if err := s.Publish(ctx, event.Action(), event.Subject()); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+		log.Printf("[WARN] Failed to publish post event: %v", err)
+	}
+}
+
+// Run relays published events to local subscribers until ctx is done.
+// go-redis re-establishes the subscription after connection failures.
+func (s *EventStreamService) Run(ctx context.Context) {
+	sub := s.cache.redis.Subscribe(ctx, postEventsChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event models.PostEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("[WARN] Dropping malformed post event: %v", err)
+				continue
+			}
+			s.broadcast(event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Subscribe registers a local subscriber. The channel is closed when the
+// subscriber falls too far behind or the service shuts down; the client is
+// expected to reconnect with Last-Event-ID.
+func (s *EventStreamService) Subscribe() (<-chan models.PostEvent, func()) {
+	ch := make(chan models.PostEvent, eventSubscriberBuffer)
+
+	s.mu.Lock()
+	if s.closed {
+		close(ch)
+	} else {
+		s.subscribers[ch] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close disconnects all subscribers so open SSE responses finish and the
+// HTTP server can shut down.
+func (s *EventStreamService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (s *EventStreamService) broadcast(event models.PostEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Replay returns the events recorded after lastID. complete is false when
+// lastID is older than the oldest retained entry, meaning events may have
+// been trimmed and the client should resynchronise.
+func (s *EventStreamService) Replay(ctx context.Context, lastID string) (events []models.PostEvent, complete bool, err error) {
+	if !ValidEventID(lastID) {
+		return nil, false, fmt.Errorf("invalid event ID %q", lastID)
+	}
+
+	err = s.cache.call(func() error {
+		oldest, err := s.cache.redis.XRangeN(ctx, postEventsStreamKey, "-", "+", 1).Result()
+		if err != nil {
+			return err
+		}
+		complete = len(oldest) == 0 || !EventIDAfter(oldest[0].ID, lastID)
+
+		entries, err := s.cache.redis.XRangeN(ctx, postEventsStreamKey, "("+lastID, "+", postEventsReplayLimit).Result()
+		if err != nil {
+			return err
+		}
+		if len(entries) == postEventsReplayLimit {
+			complete = false
+		}
+
+		events = make([]models.PostEvent, 0, len(entries))
+		for _, entry := range entries {
+			raw, _ := entry.Values["event"].(string)
+			var event models.PostEvent
+			if err := json.Unmarshal([]byte(raw), &event); err != nil {
+				continue
+			}
+			event.ID = entry.ID
+			events = append(events, event)
+		}
+		return nil
+	})
+
+	return events, complete, err
+}
+
+// ValidEventID reports whether id has the Redis stream "<ms>-<seq>" form.
+func ValidEventID(id string) bool {
+	_, _, ok := parseEventID(id)
+	return ok
+}
+
+// EventIDAfter reports whether stream entry ID a sorts after b.
+func EventIDAfter(a, b string) bool {
+	aMs, aSeq, _ := parseEventID(a)
+	bMs, bSeq, _ := parseEventID(b)
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
+
+func parseEventID(id string) (uint64, uint64, bool) {
+	msStr, seqStr, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
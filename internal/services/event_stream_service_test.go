@@ -0,0 +1,37 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestPostEventPayloadOmitsDeletedContent(t *testing.T) {
+	post := &models.Post{ID: uuid.New(), Title: "Secret", Content: "Draft body", ContentHTML: "<p>Draft body</p>", Version: 3}
+
+	data, err := json.Marshal(postEventPayload(models.ActionDeletePost, post))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+	if len(got) != 2 || got["id"] != post.ID.String() || got["version"] != float64(3) {
+		t.Errorf("delete payload = %s, want only id and version", data)
+	}
+
+	data, err = json.Marshal(postEventPayload(models.ActionUpdatePost, post))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got = nil
+	json.Unmarshal(data, &got)
+	if _, ok := got["content_html"]; ok {
+		t.Errorf("update payload = %s, want no content_html", data)
+	}
+	if got["title"] != "Secret" {
+		t.Errorf("update payload = %s, want the post", data)
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"blog/internal/models"
+)
+
+const (
+	feedCacheKeyPrefix   = "feed:posts:"
+	feedCacheTTL         = 10 * time.Minute
+	feedChangedKeyPrefix = "feed:changed:"
+	feedChangedTTL       = 7 * 24 * time.Hour
+)
+
+// FeedService serves the newest posts for the syndication feeds, caching
+// each feed's posts until a post in it may have changed. It also records
+// when each feed last changed, for Last-Modified.
+type FeedService struct {
+	posts PostRepository
+	cache JSONCache
+	size  int
+}
+
+func NewFeedService(posts PostRepository, cache JSONCache, size int) *FeedService {
+	return &FeedService{posts: posts, cache: cache, size: size}
+}
+
+// RecentPosts returns the newest posts, only those tagged tag unless it is
+// empty, and when that feed last changed.
+func (s *FeedService) RecentPosts(ctx context.Context, tag string) ([]models.Post, time.Time, error) {
+	key := feedCacheKey(tag)
+
+	var posts []models.Post
+	if err := s.cache.GetJSON(ctx, key, &posts); err != nil {
+		posts, err = s.posts.ListRecent(ctx, tag, s.size)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to list recent posts: %w", err)
+		}
+
+		if err := s.cache.SetJSON(ctx, key, posts, feedCacheTTL); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+			log.Printf("[WARN] Failed to cache feed: %v", err)
+		}
+	}
+
+	return posts, s.changedAt(ctx, tag), nil
+}
+
+// changedAt returns when the feed last changed. When that is not known,
+// e.g. it expired or Redis lost it, the feed is taken to change now.
+func (s *FeedService) changedAt(ctx context.Context, tag string) time.Time {
+	var changed time.Time
+	if err := s.cache.GetJSON(ctx, feedChangedKey(tag), &changed); err == nil {
+		return changed
+	}
+
+	changed = time.Now().UTC()
+	s.markChanged(ctx, changed, tag)
+	return changed
+}
+
+func (s *FeedService) markChanged(ctx context.Context, changed time.Time, tags ...string) {
+	for _, tag := range tags {
+		if err := s.cache.SetJSON(ctx, feedChangedKey(tag), changed, feedChangedTTL); err != nil {
+			if !errors.Is(err, ErrCacheUnavailable) {
+				log.Printf("[WARN] Failed to record feed change: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// HandlePostEvent drops the cached feeds the post appears in, before or
+// after the change, and marks them changed: the post entered, left or
+// changed within each of them.
+func (s *FeedService) HandlePostEvent(ctx context.Context, event PostEvent) {
+	tags := append([]string{}, event.Subject().Tags...)
+	if updated, ok := event.(PostUpdated); ok {
+		if change, ok := updated.Changes["tags"]; ok {
+			if old, ok := change.Old.([]string); ok {
+				tags = append(tags, old...)
+			}
+		}
+	}
+
+	tags = append(tags, "")
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = feedCacheKey(tag)
+	}
+	s.markChanged(ctx, time.Now().UTC(), tags...)
+	if err := s.cache.Delete(ctx, keys...); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+		log.Printf("[WARN] Failed to invalidate cached feeds: %v", err)
+	}
+}
+
+func feedCacheKey(tag string) string {
+	return feedCacheKeyPrefix + tag
+}
+
+func feedChangedKey(tag string) string {
+	return feedChangedKeyPrefix + tag
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+)
+
+// Counters are published through expvar and served by the admin metrics
+// endpoint.
+var postEventCounts = expvar.NewMap("post_events_total")
+
+// searchIndexing counts requests that write to the search index, the
+// documents they carry, failures, and a cumulative latency histogram in
+// milliseconds.
+var (
+	searchIndexing   = expvar.NewMap("search_indexing")
+	pendingIndexDocs = new(expvar.Int)
+
+	indexLatencyBucketsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 5000}
+)
+
+func init() {
+	searchIndexing.Set("pending", pendingIndexDocs)
+}
+
+// RecordPostEventMetrics counts committed post events by action.
+func RecordPostEventMetrics(ctx context.Context, event PostEvent) {
+	postEventCounts.Add(event.Action(), 1)
+}
+
+// recordIndexing is deferred by index writes with a pointer to their named
+// error result.
+func recordIndexing(start time.Time, docs int, err *error) {
+	ms := time.Since(start).Milliseconds()
+
+	searchIndexing.Add("requests", 1)
+	searchIndexing.Add("documents", int64(docs))
+	searchIndexing.Add("latency_ms_sum", ms)
+	if *err != nil {
+		searchIndexing.Add("errors", 1)
+	}
+	for _, le := range indexLatencyBucketsMs {
+		if ms <= le {
+			searchIndexing.Add(fmt.Sprintf("latency_ms_le_%d", le), 1)
+		}
+	}
+	searchIndexing.Add("latency_ms_le_inf", 1)
+}
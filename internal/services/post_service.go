@@ -1,160 +1,463 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
 	"time"
 
+	"blog/internal/database"
+	"blog/internal/markup"
 	"blog/internal/models"
+	"blog/internal/patch"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
+// maxUpdateAttempts bounds retries of unconditional updates that race with
+// another writer.
+const maxUpdateAttempts = 3
+
+// PostService owns post writes. Everything else that reacts to a change
+// (activity log, webhooks, cache, search, live events) subscribes to the
+// EventBus.
 type PostService struct {
-	db          *gorm.DB
-	cache       *CacheService
-	searchSvc   *SearchService
-	activitySvc *ActivityService
+	uow    UnitOfWork
+	posts  PostRepository
+	cache  PostCache
+	events *EventBus
 }
 
-func NewPostService(db *gorm.DB, cache *CacheService, searchSvc *SearchService, activitySvc *ActivityService) *PostService {
+func NewPostService(uow UnitOfWork, posts PostRepository, cache PostCache, events *EventBus) *PostService {
 	return &PostService{
-		db:          db,
-		cache:       cache,
-		searchSvc:   searchSvc,
-		activitySvc: activitySvc,
+		uow:    uow,
+		posts:  posts,
+		cache:  cache,
+		events: events,
 	}
 }
 
 func (s *PostService) CreatePost(ctx context.Context, req *models.PostCreateRequest) (*models.Post, error) {
+	now := time.Now()
+	post, event, err := s.createPost(ctx, uuid.New(), req, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, event)
+
+	return post, nil
+}
+
+// createPost, update and deletePost make a change and run its TxHandlers
+// but leave publishing the committed event to the caller.
+func (s *PostService) createPost(ctx context.Context, id uuid.UUID, req *models.PostCreateRequest, createdAt, updatedAt time.Time) (*models.Post, PostEvent, error) {
 	post := &models.Post{
-		ID:        uuid.New(),
-		Title:     req.Title,
-		Content:   req.Content,
-		Tags:      req.Tags,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            id,
+		Title:         req.Title,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Tags:          req.Tags,
+		Version:       1,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+	}
+	if post.ContentFormat == "" {
+		post.ContentFormat = markup.Markdown
 	}
+	if err := renderContent(post); err != nil {
+		return nil, nil, err
+	}
+	event := PostCreated{Post: post}
 
-	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(post).Error; err != nil {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.posts.Create(ctx, post); err != nil {
+			if errors.Is(err, database.ErrDuplicate) {
+				return fmt.Errorf("%w: post %s already exists", ErrConflict, post.ID)
+			}
 			return fmt.Errorf("failed to create post: %w", err)
 		}
-
-		if err := s.activitySvc.LogActivity(ctx, tx, models.ActionCreatePost, post.ID); err != nil {
-			return fmt.Errorf("failed to log activity: %w", err)
-		}
-		return nil
+		return s.events.PublishTx(ctx, event)
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, event, nil
+}
+
+func (s *PostService) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	if post, err := s.cache.GetPost(ctx, id); err == nil && post != nil {
+		return post, nil
+	}
+
+	post, err := s.getPost(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.searchSvc.IndexPost(ctx, post); err != nil {
-		fmt.Printf("[WARN] Failed to index post in Elasticsearch: %v\n", err)
+	if err := s.cache.SetPost(ctx, post); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+		log.Printf("[WARN] Failed to cache post: %v", err)
 	}
 
 	return post, nil
 }
 
-func (s *PostService) GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
-	if post, err := s.cache.GetPost(ctx, id); err == nil && post != nil {
-		return post, nil
+// Precondition is the If-Match condition of a write. A nil Versions makes
+// the write unconditional; otherwise the post's current version must be
+// one of Versions.
+type Precondition struct {
+	Versions []int64
+}
+
+func (p Precondition) matches(version int64) bool {
+	return p.Versions == nil || slices.Contains(p.Versions, version)
+}
+
+func (s *PostService) UpdatePost(ctx context.Context, id uuid.UUID, req *models.PostUpdateRequest, pre Precondition) (*models.Post, error) {
+	return s.updatePost(ctx, id, pre, applyUpdateRequest(req))
+}
+
+func applyUpdateRequest(req *models.PostUpdateRequest) func(post *models.Post) (models.FieldChanges, error) {
+	return func(post *models.Post) (models.FieldChanges, error) {
+		doc := post.ToDocument()
+		if req.Title != nil {
+			doc.Title = *req.Title
+		}
+		if req.Content != nil {
+			doc.Content = *req.Content
+		}
+		if req.Tags != nil {
+			doc.Tags = req.Tags
+		}
+		if req.ContentFormat != nil {
+			doc.ContentFormat = *req.ContentFormat
+		}
+		return applyDocument(post, doc), nil
 	}
+}
 
-	var post models.Post
-	if err := s.db.WithContext(ctx).First(&post, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("post not found")
+// PatchPost applies a JSON Merge Patch or JSON Patch to the post's
+// document. Array indexes in a JSON Patch refer to the version the patch is
+// applied to, so clients editing tags by index should send If-Match.
+func (s *PostService) PatchPost(ctx context.Context, id uuid.UUID, p patch.Patch, pre Precondition) (*models.Post, error) {
+	return s.updatePost(ctx, id, pre, func(post *models.Post) (models.FieldChanges, error) {
+		data, err := json.Marshal(post.ToDocument())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode post: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get post: %w", err)
+		data, err = p.Apply(data)
+		if errors.Is(err, patch.ErrTestFailed) {
+			return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		var doc models.PostPatchDocument
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if doc.Title == "" || doc.Content == "" {
+			return nil, fmt.Errorf("%w: title and content are required", ErrInvalidPatch)
+		}
+		if !markup.Valid(doc.ContentFormat) {
+			return nil, fmt.Errorf("%w: content_format must be markdown, html or plain", ErrInvalidPatch)
+		}
+		doc.Tags = editTags(doc.Tags, doc.AddTags, doc.RemoveTags)
+		return applyDocument(post, doc.PostDocument), nil
+	})
+}
+
+// editTags appends the added tags that are missing and then drops the
+// removed ones. Removing a tag the post does not have is not an error.
+func editTags(tags, add, remove []string) []string {
+	if len(add) == 0 && len(remove) == 0 {
+		return tags
 	}
 
-	if err := s.cache.SetPost(ctx, &post); err != nil {
-		fmt.Printf("Failed to cache post: %v\n", err)
+	edited := make([]string, 0, len(tags)+len(add))
+	for _, tag := range append(append([]string{}, tags...), add...) {
+		if !slices.Contains(edited, tag) && !slices.Contains(remove, tag) {
+			edited = append(edited, tag)
+		}
 	}
+	return edited
+}
 
-	return &post, nil
+// applyDocument copies doc onto post and returns what changed.
+func applyDocument(post *models.Post, doc models.PostDocument) models.FieldChanges {
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+
+	changes := models.FieldChanges{}
+	if doc.Title != post.Title {
+		changes["title"] = models.FieldChange{Old: post.Title, New: doc.Title}
+		post.Title = doc.Title
+	}
+	if doc.Content != post.Content {
+		changes["content"] = models.FieldChange{Old: post.Content, New: doc.Content}
+		post.Content = doc.Content
+	}
+	if doc.ContentFormat != post.ContentFormat {
+		changes["content_format"] = models.FieldChange{Old: post.ContentFormat, New: doc.ContentFormat}
+		post.ContentFormat = doc.ContentFormat
+	}
+	if !slices.Equal(doc.Tags, post.Tags) {
+		changes["tags"] = models.FieldChange{Old: []string(post.Tags), New: doc.Tags}
+		post.Tags = doc.Tags
+	}
+	return changes
 }
 
-func (s *PostService) UpdatePost(ctx context.Context, id uuid.UUID, req *models.PostUpdateRequest) (*models.Post, error) {
-	var post models.Post
-	if err := s.db.WithContext(ctx).First(&post, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("post not found")
-		}
-		return nil, err
+// renderContent refreshes the post's sanitized HTML and the summary
+// derived from it. Rendering on every write also upgrades posts stored
+// before a sanitizer change.
+func renderContent(post *models.Post) error {
+	rendered, err := markup.Render(post.ContentFormat, post.Content)
+	if errors.Is(err, markup.ErrUnknownFormat) {
+		return fmt.Errorf("%w: %v", ErrInvalidContentFormat, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render content: %w", err)
 	}
+	post.ContentHTML = rendered.HTML
+	post.Excerpt = rendered.Excerpt
+	post.WordCount = rendered.WordCount
+	post.ReadingTimeMinutes = rendered.ReadingTimeMinutes
+	post.TOC = rendered.TOC
+	return nil
+}
 
-	if req.Title != nil {
-		post.Title = *req.Title
+func (s *PostService) updatePost(ctx context.Context, id uuid.UUID, pre Precondition, apply func(post *models.Post) (models.FieldChanges, error)) (*models.Post, error) {
+	post, event, err := s.update(ctx, id, pre, apply)
+	if err != nil {
+		return nil, err
 	}
-	if req.Content != nil {
-		post.Content = *req.Content
+
+	s.events.Publish(ctx, event)
+
+	return post, nil
+}
+
+// update applies apply to the current post and saves it if the stored
+// version has not moved on in the meantime. Unconditional updates that
+// lose that race are retried on a fresh copy; conditional ones fail with
+// ErrPostModified.
+func (s *PostService) update(ctx context.Context, id uuid.UUID, pre Precondition, apply func(post *models.Post) (models.FieldChanges, error)) (*models.Post, PostEvent, error) {
+	for attempt := 1; ; attempt++ {
+		post, err := s.getPost(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !pre.matches(post.Version) {
+			return nil, nil, ErrPostModified
+		}
+
+		changes, err := apply(post)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := renderContent(post); err != nil {
+			return nil, nil, err
+		}
+		post.UpdatedAt = time.Now()
+		event := PostUpdated{Post: post, Changes: changes}
+
+		err = s.uow.Do(ctx, func(ctx context.Context) error {
+			if err := s.posts.Update(ctx, post); err != nil {
+				return err
+			}
+			return s.events.PublishTx(ctx, event)
+		})
+		if errors.Is(err, database.ErrStale) {
+			if pre.Versions == nil && attempt < maxUpdateAttempts {
+				continue
+			}
+			return nil, nil, ErrPostModified
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update post: %w", err)
+		}
+		return post, event, nil
 	}
-	if req.Tags != nil {
-		post.Tags = req.Tags
+}
+
+func (s *PostService) DeletePost(ctx context.Context, id uuid.UUID, pre Precondition) error {
+	event, err := s.deletePost(ctx, id, pre)
+	if err != nil {
+		return err
 	}
-	post.UpdatedAt = time.Now()
 
-	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Save(&post).Error; err != nil {
-			return fmt.Errorf("failed to update post: %w", err)
+	s.events.Publish(ctx, event)
+
+	return nil
+}
+
+func (s *PostService) deletePost(ctx context.Context, id uuid.UUID, pre Precondition) (PostEvent, error) {
+	var event PostDeleted
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var version int64
+		if pre.Versions != nil {
+			current, err := s.getPost(ctx, id)
+			if err != nil {
+				return err
+			}
+			if !pre.matches(current.Version) {
+				return ErrPostModified
+			}
+			version = current.Version
+		}
+
+		post, err := s.posts.Delete(ctx, id, version)
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrPostNotFound
 		}
-		if err := s.activitySvc.LogActivity(ctx, tx, models.ActionUpdatePost, post.ID); err != nil {
-			return fmt.Errorf("failed to log activity: %w", err)
+		if errors.Is(err, database.ErrStale) {
+			return ErrPostModified
 		}
-		return nil
+		if err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+		event = PostDeleted{Post: post}
+		return s.events.PublishTx(ctx, event)
 	})
 	if err != nil {
 		return nil, err
 	}
+	return event, nil
+}
+
+// BulkResult is the outcome of one BulkOperation. Post is nil for deletes
+// and failed operations.
+type BulkResult struct {
+	Post *models.Post
+	Err  error
+}
+
+// Bulk runs ops in order. An atomic bulk runs in one transaction and stops
+// at the first failure, rolling everything back; otherwise each operation
+// commits on its own. Post-commit handlers get all committed changes in
+// one PublishAll, so the search index is updated with a single request.
+func (s *PostService) Bulk(ctx context.Context, ops []models.BulkOperation, atomic bool) []BulkResult {
+	results := make([]BulkResult, len(ops))
+	var events []PostEvent
 
-	if err := s.cache.DeletePost(ctx, id); err != nil {
-		fmt.Printf("Failed to invalidate cache: %v\n", err)
+	if !atomic {
+		for i, op := range ops {
+			post, event, err := s.bulkOperation(ctx, op)
+			results[i] = BulkResult{Post: post, Err: err}
+			if err == nil {
+				events = append(events, event)
+			}
+		}
+		s.events.PublishAll(ctx, events)
+		return results
 	}
 
-	if err := s.searchSvc.IndexPost(ctx, &post); err != nil {
-		fmt.Printf("Failed to update Elasticsearch: %v\n", err)
+	failed := -1
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		for i, op := range ops {
+			post, event, err := s.bulkOperation(ctx, op)
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i].Post = post
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range results {
+			switch {
+			case i == failed:
+				results[i] = BulkResult{Err: err}
+			case failed < 0:
+				results[i] = BulkResult{Err: fmt.Errorf("failed to commit bulk: %w", err)}
+			default:
+				results[i] = BulkResult{Err: ErrBulkRolledBack}
+			}
+		}
+		return results
 	}
 
-	return &post, nil
+	s.events.PublishAll(ctx, events)
+	return results
 }
 
-func (s *PostService) DeletePost(ctx context.Context, id uuid.UUID) error {
-    err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-        if err := s.activitySvc.LogActivity(ctx, tx, models.ActionDeletePost, id); err != nil {
-            return fmt.Errorf("failed to log activity: %w", err)
-        }
-
-        if err := tx.Delete(&models.Post{}, "id = ?", id).Error; err != nil {
-            return fmt.Errorf("failed to delete post: %w", err)
-        }
-
-        return nil
-    })
-    if err != nil {
-        return err
-    }
+func (s *PostService) bulkOperation(ctx context.Context, op models.BulkOperation) (*models.Post, PostEvent, error) {
+	var pre Precondition
+	if op.Version != 0 {
+		pre.Versions = []int64{op.Version}
+	}
 
-    if err := s.cache.DeletePost(ctx, id); err != nil {
-        fmt.Printf("Failed to remove from cache: %v\n", err)
-    }
-    if err := s.searchSvc.DeletePost(ctx, id); err != nil {
-        fmt.Printf("Failed to remove from Elasticsearch: %v\n", err)
-    }
+	id := uuid.New()
+	if op.ID != "" {
+		var err error
+		if id, err = uuid.Parse(op.ID); err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid id %q", ErrInvalidBulkOperation, op.ID)
+		}
+	} else if op.Op != models.BulkCreate {
+		return nil, nil, fmt.Errorf("%w: %s requires an id", ErrInvalidBulkOperation, op.Op)
+	}
 
-    return nil
+	switch op.Op {
+	case models.BulkCreate:
+		if op.Title == nil || *op.Title == "" || op.Content == nil || *op.Content == "" {
+			return nil, nil, fmt.Errorf("%w: create requires title and content", ErrInvalidBulkOperation)
+		}
+		createdAt, updatedAt := time.Now(), time.Now()
+		if op.CreatedAt != nil {
+			createdAt, updatedAt = *op.CreatedAt, *op.CreatedAt
+		}
+		if op.UpdatedAt != nil {
+			updatedAt = *op.UpdatedAt
+		}
+		req := &models.PostCreateRequest{Title: *op.Title, Content: *op.Content, Tags: op.Tags}
+		if op.ContentFormat != nil {
+			req.ContentFormat = *op.ContentFormat
+		}
+		return s.createPost(ctx, id, req, createdAt, updatedAt)
+	case models.BulkUpdate:
+		return s.update(ctx, id, pre, applyUpdateRequest(&models.PostUpdateRequest{Title: op.Title, Content: op.Content, Tags: op.Tags, ContentFormat: op.ContentFormat}))
+	case models.BulkDelete:
+		event, err := s.deletePost(ctx, id, pre)
+		return nil, event, err
+	}
+	return nil, nil, fmt.Errorf("%w: unknown op %q", ErrInvalidBulkOperation, op.Op)
 }
 
 func (s *PostService) SearchByTag(ctx context.Context, tag string) ([]models.Post, error) {
-	var posts []models.Post
-	if err := s.db.WithContext(ctx).
-		Where("? = ANY(tags)", tag).
-		Order("created_at DESC").
-		Find(&posts).Error; err != nil {
+	posts, err := s.posts.ListByTag(ctx, tag)
+	if err != nil {
 		return nil, fmt.Errorf("failed to search posts by tag: %w", err)
 	}
 	return posts, nil
 }
+
+// ExportPosts calls fn for every post, oldest first.
+func (s *PostService) ExportPosts(ctx context.Context, fn func(post *models.Post) error) error {
+	if err := s.posts.Each(ctx, fn); err != nil {
+		return fmt.Errorf("failed to export posts: %w", err)
+	}
+	return nil
+}
+
+func (s *PostService) getPost(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	post, err := s.posts.Get(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return post, nil
+}
@@ -0,0 +1,299 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"blog/internal/database"
+	"blog/internal/memory"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+)
+
+type postServiceFixture struct {
+	svc       *services.PostService
+	posts     *memory.PostRepository
+	cache     *memory.Cache
+	index     *memory.SearchIndex
+	events    *services.EventBus
+	published []services.PostEvent
+}
+
+func newPostServiceFixture(t *testing.T) *postServiceFixture {
+	t.Helper()
+
+	f := &postServiceFixture{
+		posts:  memory.NewPostRepository(),
+		cache:  memory.NewCache(),
+		index:  memory.NewSearchIndex(),
+		events: services.NewEventBus(),
+	}
+	f.events.Subscribe(services.InvalidateCachedPosts(f.cache))
+	f.events.SubscribeBatch(services.SyncSearchIndex(f.index))
+	f.events.Subscribe(func(ctx context.Context, event services.PostEvent) {
+		f.published = append(f.published, event)
+	})
+	f.svc = services.NewPostService(memory.NewUnitOfWork(f.posts), f.posts, f.cache, f.events)
+	return f
+}
+
+func (f *postServiceFixture) create(t *testing.T, title string, tags ...string) *models.Post {
+	t.Helper()
+
+	post, err := f.svc.CreatePost(context.Background(), &models.PostCreateRequest{
+		Title:   title,
+		Content: "content of " + title,
+		Tags:    tags,
+	})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return post
+}
+
+func (f *postServiceFixture) searchTotal(t *testing.T, query string) int64 {
+	t.Helper()
+
+	res, err := f.index.SearchPosts(context.Background(), &models.PostSearchRequest{Query: query})
+	if err != nil {
+		t.Fatalf("SearchPosts: %v", err)
+	}
+	return res.TotalCount
+}
+
+func TestCreatePost(t *testing.T) {
+	f := newPostServiceFixture(t)
+
+	post := f.create(t, "Hello", "go")
+
+	stored, err := f.posts.Get(context.Background(), post.ID)
+	if err != nil {
+		t.Fatalf("post not stored: %v", err)
+	}
+	if stored.Title != "Hello" || len(stored.Tags) != 1 || stored.Tags[0] != "go" {
+		t.Errorf("stored post = %+v", stored)
+	}
+	if got := f.searchTotal(t, "hello"); got != 1 {
+		t.Errorf("indexed posts = %d, want 1", got)
+	}
+	if len(f.published) != 1 {
+		t.Fatalf("published %d events, want 1", len(f.published))
+	}
+	if _, ok := f.published[0].(services.PostCreated); !ok {
+		t.Errorf("published %T, want PostCreated", f.published[0])
+	}
+}
+
+func TestCreatePostRollsBackWhenTxHandlerFails(t *testing.T) {
+	f := newPostServiceFixture(t)
+	failure := errors.New("audit log down")
+	f.events.SubscribeTx(func(ctx context.Context, event services.PostEvent) error {
+		return failure
+	})
+
+	_, err := f.svc.CreatePost(context.Background(), &models.PostCreateRequest{Title: "Hello", Content: "x"})
+	if !errors.Is(err, failure) {
+		t.Fatalf("CreatePost error = %v, want %v", err, failure)
+	}
+	if n := f.posts.Len(); n != 0 {
+		t.Errorf("store has %d posts after rollback", n)
+	}
+	if len(f.published) != 0 {
+		t.Errorf("published %d events for a rolled back change", len(f.published))
+	}
+	if got := f.searchTotal(t, ""); got != 0 {
+		t.Errorf("indexed posts = %d after rollback, want 0", got)
+	}
+}
+
+func TestGetPostFillsCache(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello")
+
+	got, err := f.svc.GetPost(context.Background(), post.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if got.ID != post.ID {
+		t.Errorf("GetPost returned %s, want %s", got.ID, post.ID)
+	}
+	if !f.cache.CachedPost(post.ID) {
+		t.Error("post was not cached")
+	}
+}
+
+func TestGetPostWithoutCache(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello")
+	f.cache.Unavailable = true
+
+	if _, err := f.svc.GetPost(context.Background(), post.ID); err != nil {
+		t.Fatalf("GetPost with cache down: %v", err)
+	}
+}
+
+func TestGetPostNotFound(t *testing.T) {
+	f := newPostServiceFixture(t)
+
+	_, err := f.svc.GetPost(context.Background(), uuid.New())
+	if !errors.Is(err, services.ErrPostNotFound) {
+		t.Fatalf("GetPost error = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello", "go")
+	if _, err := f.svc.GetPost(context.Background(), post.ID); err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+
+	var changes models.FieldChanges
+	f.events.SubscribeTx(func(ctx context.Context, event services.PostEvent) error {
+		if e, ok := event.(services.PostUpdated); ok {
+			changes = e.Changes
+		}
+		return nil
+	})
+
+	title := "Goodbye"
+	updated, err := f.svc.UpdatePost(context.Background(), post.ID, &models.PostUpdateRequest{
+		Title: &title,
+		Tags:  []string{"go"},
+	}, services.Precondition{})
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if updated.Title != title {
+		t.Errorf("title = %q, want %q", updated.Title, title)
+	}
+	if updated.Version != post.Version+1 {
+		t.Errorf("version = %d, want %d", updated.Version, post.Version+1)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("changes = %v, want only title", changes)
+	}
+	if c := changes["title"]; c.Old != "Hello" || c.New != title {
+		t.Errorf("title change = %+v", c)
+	}
+	if f.cache.CachedPost(post.ID) {
+		t.Error("updated post is still cached")
+	}
+	if got := f.searchTotal(t, "goodbye"); got != 1 {
+		t.Errorf("search for new title found %d posts, want 1", got)
+	}
+}
+
+func TestUpdatePostNotFound(t *testing.T) {
+	f := newPostServiceFixture(t)
+
+	title := "x"
+	_, err := f.svc.UpdatePost(context.Background(), uuid.New(), &models.PostUpdateRequest{Title: &title}, services.Precondition{})
+	if !errors.Is(err, services.ErrPostNotFound) {
+		t.Fatalf("UpdatePost error = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello")
+	if _, err := f.svc.GetPost(context.Background(), post.ID); err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+
+	if err := f.svc.DeletePost(context.Background(), post.ID, services.Precondition{}); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	if _, err := f.posts.Get(context.Background(), post.ID); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("post still stored: %v", err)
+	}
+	if f.cache.CachedPost(post.ID) {
+		t.Error("deleted post is still cached")
+	}
+	if got := f.searchTotal(t, ""); got != 0 {
+		t.Errorf("indexed posts = %d, want 0", got)
+	}
+
+	last := f.published[len(f.published)-1]
+	deleted, ok := last.(services.PostDeleted)
+	if !ok {
+		t.Fatalf("last event is %T, want PostDeleted", last)
+	}
+	if deleted.Post.Title != "Hello" {
+		t.Errorf("PostDeleted carries title %q, want the deleted post's", deleted.Post.Title)
+	}
+}
+
+func TestSearchByTag(t *testing.T) {
+	f := newPostServiceFixture(t)
+	f.create(t, "One", "go")
+	f.create(t, "Two", "rust")
+	f.create(t, "Three", "go", "rust")
+
+	posts, err := f.svc.SearchByTag(context.Background(), "go")
+	if err != nil {
+		t.Fatalf("SearchByTag: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("found %d posts, want 2", len(posts))
+	}
+	if posts[0].Title != "Three" {
+		t.Errorf("first post = %q, want newest first", posts[0].Title)
+	}
+}
+
+func TestDeletePostNotFound(t *testing.T) {
+	f := newPostServiceFixture(t)
+
+	err := f.svc.DeletePost(context.Background(), uuid.New(), services.Precondition{})
+	if !errors.Is(err, services.ErrNotFound) {
+		t.Fatalf("DeletePost error = %v, want ErrNotFound", err)
+	}
+	if len(f.published) != 0 {
+		t.Errorf("published %d events for a missing post", len(f.published))
+	}
+}
+
+func TestUpdatePostPrecondition(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello")
+	title := "Goodbye"
+	req := &models.PostUpdateRequest{Title: &title}
+
+	_, err := f.svc.UpdatePost(context.Background(), post.ID, req, services.Precondition{Versions: []int64{post.Version + 1}})
+	if !errors.Is(err, services.ErrPreconditionFailed) {
+		t.Fatalf("stale update error = %v, want ErrPreconditionFailed", err)
+	}
+
+	if _, err := f.svc.UpdatePost(context.Background(), post.ID, req, services.Precondition{Versions: []int64{post.Version}}); err != nil {
+		t.Fatalf("update with current version: %v", err)
+	}
+
+	// The version read by the first editor is now stale.
+	_, err = f.svc.UpdatePost(context.Background(), post.ID, req, services.Precondition{Versions: []int64{post.Version}})
+	if !errors.Is(err, services.ErrPostModified) {
+		t.Fatalf("second update error = %v, want ErrPostModified", err)
+	}
+}
+
+func TestDeletePostPrecondition(t *testing.T) {
+	f := newPostServiceFixture(t)
+	post := f.create(t, "Hello")
+
+	err := f.svc.DeletePost(context.Background(), post.ID, services.Precondition{Versions: []int64{}})
+	if !errors.Is(err, services.ErrPreconditionFailed) {
+		t.Fatalf("delete with no matching version: %v, want ErrPreconditionFailed", err)
+	}
+	if f.posts.Len() != 1 {
+		t.Fatal("post deleted despite failed precondition")
+	}
+
+	if err := f.svc.DeletePost(context.Background(), post.ID, services.Precondition{Versions: []int64{post.Version}}); err != nil {
+		t.Fatalf("delete with current version: %v", err)
+	}
+}
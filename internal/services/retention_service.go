@@ -0,0 +1,226 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/database"
+	"blog/internal/models"
+)
+
+// RetentionService keeps activity_logs bounded: it pre-creates monthly
+// partitions, archives partitions that no retention policy needs any more
+// before dropping them, and archives then deletes the remaining entries past
+// their action's retention.
+type RetentionService struct {
+	uow  UnitOfWork
+	logs *database.ActivityLogRepository
+	cfg  *config.ActivityConfig
+}
+
+func NewRetentionService(uow UnitOfWork, logs *database.ActivityLogRepository, cfg *config.ActivityConfig) *RetentionService {
+	return &RetentionService{uow: uow, logs: logs, cfg: cfg}
+}
+
+func (s *RetentionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("[WARN] Activity log maintenance failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *RetentionService) RunOnce(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	if err := s.logs.EnsurePartitions(ctx, now); err != nil {
+		return err
+	}
+	if err := s.archiveExpiredPartitions(ctx, now); err != nil {
+		return err
+	}
+	return s.purgeExpired(ctx, now)
+}
+
+// expiredActivity selects the entries past their retention. Actions kept
+// forever are never selected.
+func (s *RetentionService) expiredActivity(now time.Time) []database.ActivityLogExpiry {
+	var expired []database.ActivityLogExpiry
+	overridden := make([]string, 0, len(s.cfg.Retention))
+	for action, period := range s.cfg.Retention {
+		overridden = append(overridden, action)
+		if period > 0 {
+			expired = append(expired, database.ActivityLogExpiry{Action: action, Before: now.Add(-period)})
+		}
+	}
+
+	if s.cfg.DefaultRetention > 0 {
+		expired = append(expired, database.ActivityLogExpiry{
+			ExceptActions: overridden,
+			Before:        now.Add(-s.cfg.DefaultRetention),
+		})
+	}
+
+	return expired
+}
+
+// purgeExpired archives and deletes entries past their retention. The rows
+// are deleted in one transaction that only commits once the archive is
+// complete, so an entry is never deleted without being archived.
+func (s *RetentionService) purgeExpired(ctx context.Context, now time.Time) error {
+	expired := s.expiredActivity(now)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s_expired_%s", models.ActivityLog{}.TableName(), now.Format("20060102T150405"))
+	var path string
+	var total int64
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		path, err = s.writeArchive(name, func(enc *json.Encoder) error {
+			for _, expiry := range expired {
+				n, err := s.logs.DeleteExpired(ctx, expiry, func(entry *models.ActivityLog) error {
+					return enc.Encode(entry.ToResponse())
+				})
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive expired activity: %w", err)
+		}
+		if total == 0 {
+			os.Remove(path)
+			path = ""
+		}
+		return nil
+	})
+	if err != nil {
+		if path != "" {
+			// The rows were not deleted, so the archive would duplicate
+			// the next run's.
+			os.Remove(path)
+		}
+		return err
+	}
+
+	if total > 0 {
+		log.Printf("Archived %d expired activity logs to %s", total, path)
+	}
+	return nil
+}
+
+// archiveExpiredPartitions drops partitions that lie entirely beyond the
+// longest retention period. No partition is dropped while any policy keeps
+// logs forever; purgeExpired still archives the other actions' entries.
+func (s *RetentionService) archiveExpiredPartitions(ctx context.Context, now time.Time) error {
+	longest := s.cfg.DefaultRetention
+	if longest <= 0 {
+		return nil
+	}
+	for _, period := range s.cfg.Retention {
+		if period <= 0 {
+			return nil
+		}
+		if period > longest {
+			longest = period
+		}
+	}
+	cutoff := now.Add(-longest)
+
+	partitions, err := s.logs.Partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if p.End.After(cutoff) {
+			break
+		}
+
+		path, err := s.archivePartition(ctx, p)
+		if err != nil {
+			return err
+		}
+		if err := s.logs.DropPartition(ctx, p); err != nil {
+			return err
+		}
+		log.Printf("Archived activity log partition %s to %s", p.Name, path)
+	}
+
+	return nil
+}
+
+func (s *RetentionService) archivePartition(ctx context.Context, p database.ActivityLogPartition) (string, error) {
+	path, err := s.writeArchive(p.Name, func(enc *json.Encoder) error {
+		return s.logs.EachInPartition(ctx, p, func(entry *models.ActivityLog) error {
+			return enc.Encode(entry.ToResponse())
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to archive partition %s: %w", p.Name, err)
+	}
+	return path, nil
+}
+
+// writeArchive writes the entries encoded by fill to name as gzipped JSON
+// lines. The file is written under a temporary name and renamed once
+// complete so a crash never leaves a truncated archive behind deleted rows.
+func (s *RetentionService) writeArchive(name string, fill func(enc *json.Encoder) error) (string, error) {
+	if err := os.MkdirAll(s.cfg.ArchiveDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	path := filepath.Join(s.cfg.ArchiveDir, name+".jsonl.gz")
+	tmp := path + ".tmp"
+
+	if err := writeGzipLines(tmp, fill); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return path, nil
+}
+
+func writeGzipLines(path string, fill func(enc *json.Encoder) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := fill(json.NewEncoder(zw)); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"blog/internal/database"
 	"blog/internal/models"
@@ -15,11 +18,21 @@ import (
 )
 
 type SearchService struct {
-	es *database.ElasticsearchClient
+	es      *database.ElasticsearchClient
+	refresh string
 }
 
-func NewSearchService(es *database.ElasticsearchClient) *SearchService {
-	return &SearchService{es: es}
+// NewSearchService writes with the given refresh policy: "false" leaves
+// refreshing to Elasticsearch, "wait_for" blocks until the write is
+// searchable and "true" forces a refresh on every write.
+func NewSearchService(es *database.ElasticsearchClient, refresh string) *SearchService {
+	switch refresh {
+	case "true", "false", "wait_for":
+	default:
+		log.Printf("[WARN] Unknown Elasticsearch refresh policy %q, using false", refresh)
+		refresh = "false"
+	}
+	return &SearchService{es: es, refresh: refresh}
 }
 
 func (s *SearchService) InitializeIndex(ctx context.Context) error {
@@ -37,6 +50,10 @@ func (s *SearchService) InitializeIndex(ctx context.Context) error {
 		return nil
 	}
 
+	return s.createIndex(ctx)
+}
+
+func (s *SearchService) createIndex(ctx context.Context) error {
 	createReq := esapi.IndicesCreateRequest{
 		Index: database.PostsIndex,
 		Body:  strings.NewReader(database.GetPostsMapping()),
@@ -55,7 +72,145 @@ func (s *SearchService) InitializeIndex(ctx context.Context) error {
 	return nil
 }
 
-func (s *SearchService) IndexPost(ctx context.Context, post *models.Post) error {
+// UpgradeIndex brings an index created by an older release up to
+// database.PostsIndexSchema: it adds the new fields to the mapping, or
+// recreates the index when they were already mapped dynamically, and
+// reindexes every post so existing documents carry them. The schema is
+// recorded last, so an interrupted upgrade is redone on the next start.
+func (s *SearchService) UpgradeIndex(ctx context.Context, posts PostRepository) error {
+	schema, err := s.indexSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if schema >= database.PostsIndexSchema {
+		return nil
+	}
+
+	if err := s.putMapping(ctx, database.GetPostsPropertiesMapping()); err != nil {
+		log.Printf("[WARN] Recreating search index, its mapping cannot be updated in place: %v", err)
+		if err := s.recreateIndex(ctx); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.reindex(ctx, posts)
+	if err != nil {
+		return err
+	}
+	if err := s.putMapping(ctx, database.GetPostsSchemaMapping()); err != nil {
+		return err
+	}
+
+	log.Printf("Reindexed %d posts for search index schema %d", n, database.PostsIndexSchema)
+	return nil
+}
+
+func (s *SearchService) indexSchema(ctx context.Context) (int, error) {
+	req := esapi.IndicesGetMappingRequest{
+		Index: []string{database.PostsIndex},
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get index mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("failed to get index mapping: %s", res.Status())
+	}
+
+	var result map[string]struct {
+		Mappings struct {
+			Meta struct {
+				Schema int `json:"schema"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode index mapping: %w", err)
+	}
+	for _, index := range result {
+		return index.Mappings.Meta.Schema, nil
+	}
+	return 0, nil
+}
+
+func (s *SearchService) putMapping(ctx context.Context, body string) error {
+	req := esapi.IndicesPutMappingRequest{
+		Index: []string{database.PostsIndex},
+		Body:  strings.NewReader(body),
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("failed to update index mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed to update index mapping: %s", res.String())
+	}
+
+	return nil
+}
+
+func (s *SearchService) recreateIndex(ctx context.Context) error {
+	req := esapi.IndicesDeleteRequest{
+		Index: []string{database.PostsIndex},
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("failed to delete index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed to delete index: %s", res.Status())
+	}
+
+	return s.createIndex(ctx)
+}
+
+// reindexBatchSize is how many posts reindex sends per bulk request.
+const reindexBatchSize = 500
+
+func (s *SearchService) reindex(ctx context.Context, posts PostRepository) (int, error) {
+	n := 0
+	batch := make([]*models.Post, 0, reindexBatchSize)
+	flush := func() error {
+		err := s.BulkIndex(ctx, batch, nil)
+		var bulkErr *BulkIndexError
+		if errors.As(err, &bulkErr) {
+			// Rejected documents would be rejected again; reindexing
+			// the rest is better than not starting.
+			log.Printf("[WARN] Failed to reindex some posts: %v", err)
+			n -= len(bulkErr.Failed)
+		} else if err != nil {
+			return fmt.Errorf("failed to reindex posts: %w", err)
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := posts.Each(ctx, func(post *models.Post) error {
+		batch = append(batch, post)
+		if len(batch) == reindexBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	return n, err
+}
+
+func (s *SearchService) IndexPost(ctx context.Context, post *models.Post) (err error) {
+	defer recordIndexing(time.Now(), 1, &err)
+
 	doc := post.ToElasticsearchDoc()
 	data, err := json.Marshal(doc)
 	if err != nil {
@@ -66,7 +221,7 @@ func (s *SearchService) IndexPost(ctx context.Context, post *models.Post) error
 		Index:      database.PostsIndex,
 		DocumentID: post.ID.String(),
 		Body:       bytes.NewReader(data),
-		Refresh:    "true",
+		Refresh:    s.refresh,
 	}
 
 	res, err := req.Do(ctx, s.es)
@@ -82,11 +237,137 @@ func (s *SearchService) IndexPost(ctx context.Context, post *models.Post) error
 	return nil
 }
 
-func (s *SearchService) DeletePost(ctx context.Context, id uuid.UUID) error {
+func (s *SearchService) BulkIndex(ctx context.Context, upserts []*models.Post, deletes []uuid.UUID) (err error) {
+	if len(upserts) == 0 && len(deletes) == 0 {
+		return nil
+	}
+	defer recordIndexing(time.Now(), len(upserts)+len(deletes), &err)
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, post := range upserts {
+		if err := enc.Encode(map[string]interface{}{"index": map[string]string{"_id": post.ID.String()}}); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := enc.Encode(post.ToElasticsearchDoc()); err != nil {
+			return fmt.Errorf("failed to marshal post: %w", err)
+		}
+	}
+	for _, id := range deletes {
+		if err := enc.Encode(map[string]interface{}{"delete": map[string]string{"_id": id.String()}}); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+	}
+
+	req := esapi.BulkRequest{
+		Index:   database.PostsIndex,
+		Body:    &body,
+		Refresh: s.refresh,
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("failed to bulk index posts: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed to bulk index posts: %s", res.Status())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk result: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	bulkErr := &BulkIndexError{Total: len(result.Items)}
+	for _, item := range result.Items {
+		for action, r := range item {
+			if r.Status < 300 || (action == "delete" && r.Status == 404) {
+				continue
+			}
+			id, err := uuid.Parse(r.ID)
+			if err != nil {
+				return fmt.Errorf("failed to decode bulk result: %w", err)
+			}
+			bulkErr.Failed = append(bulkErr.Failed, BulkItemError{ID: id, Status: r.Status})
+		}
+	}
+	if len(bulkErr.Failed) > 0 {
+		return bulkErr
+	}
+	return nil
+}
+
+// BulkIndexError is returned by BulkIndex when the request was accepted but
+// some of its documents were not.
+type BulkIndexError struct {
+	Failed []BulkItemError
+	Total  int
+}
+
+type BulkItemError struct {
+	ID     uuid.UUID
+	Status int
+}
+
+// Retryable reports whether sending the document again may succeed:
+// Elasticsearch was overloaded or failed, rather than rejected it.
+func (e BulkItemError) Retryable() bool {
+	return e.Status == 429 || e.Status >= 500
+}
+
+func (e *BulkIndexError) Error() string {
+	ids := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		ids[i] = fmt.Sprintf("%s (%d)", f.ID, f.Status)
+	}
+	return fmt.Sprintf("failed to bulk index %d of %d posts: %s", len(e.Failed), e.Total, strings.Join(ids, ", "))
+}
+
+func (s *SearchService) UpdateViewCount(ctx context.Context, id uuid.UUID, viewCount int64) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"doc": map[string]interface{}{"view_count": viewCount},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal view count: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      database.PostsIndex,
+		DocumentID: id.String(),
+		Body:       bytes.NewReader(data),
+	}
+
+	res, err := req.Do(ctx, s.es)
+	if err != nil {
+		return fmt.Errorf("failed to update view count: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("failed to update view count: %s", res.Status())
+	}
+
+	return nil
+}
+
+func (s *SearchService) DeletePost(ctx context.Context, id uuid.UUID) (err error) {
+	defer recordIndexing(time.Now(), 1, &err)
+
 	req := esapi.DeleteRequest{
 		Index:      database.PostsIndex,
 		DocumentID: id.String(),
-		Refresh:    "true",
+		Refresh:    s.refresh,
 	}
 
 	res, err := req.Do(ctx, s.es)
@@ -112,7 +393,7 @@ func (s *SearchService) SearchPosts(ctx context.Context, req *models.PostSearchR
 
 	from := (req.Page - 1) * req.Limit
 
-	query := s.buildSearchQuery(req.Query, req.Tags)
+	query := s.buildSearchQuery(req.Query, req.Tags, req.Sort)
 
 	searchReq := esapi.SearchRequest{
 		Index: []string{database.PostsIndex},
@@ -159,16 +440,25 @@ func (s *SearchService) SearchPosts(ctx context.Context, req *models.PostSearchR
 	}, nil
 }
 
-func (s *SearchService) buildSearchQuery(queryString, tags string) string {
+func (s *SearchService) buildSearchQuery(queryString, tags, sort string) string {
+	sortFields := []map[string]interface{}{
+		{"created_at": map[string]string{"order": "desc"}},
+	}
+	if sort == models.SortPopular {
+		// unmapped_type keeps the query valid on indices created before
+		// view_count was added to the mapping.
+		sortFields = append([]map[string]interface{}{
+			{"view_count": map[string]string{"order": "desc", "unmapped_type": "long"}},
+		}, sortFields...)
+	}
+
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must": []interface{}{},
 			},
 		},
-		"sort": []map[string]interface{}{
-			{"created_at": map[string]string{"order": "desc"}},
-		},
+		"sort": sortFields,
 	}
 
 	mustQueries := []interface{}{}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/models"
+	"blog/internal/sitemap"
+)
+
+// SitemapChunkPath is the path of the nth (1-based) posts sitemap.
+const SitemapChunkPath = "/sitemaps/posts-%d.xml"
+
+// Sitemap is a rendered sitemap index and the sitemaps it lists.
+type Sitemap struct {
+	Index     []byte
+	Chunks    [][]byte
+	Generated time.Time
+}
+
+// SitemapService keeps the rendered sitemap in memory and rebuilds it from
+// PostgreSQL when posts change, so requests never scan the posts table.
+type SitemapService struct {
+	posts     PostRepository
+	site      *config.SiteConfig
+	chunkSize int
+	changed   chan struct{}
+
+	generateMu sync.Mutex
+	mu         sync.RWMutex
+	current    *Sitemap
+}
+
+func NewSitemapService(posts PostRepository, site *config.SiteConfig) *SitemapService {
+	chunkSize := site.SitemapChunkSize
+	if chunkSize <= 0 || chunkSize > sitemap.MaxURLs {
+		chunkSize = sitemap.MaxURLs
+	}
+	return &SitemapService{
+		posts:     posts,
+		site:      site,
+		chunkSize: chunkSize,
+		changed:   make(chan struct{}, 1),
+	}
+}
+
+// Sitemap returns the current sitemap, generating it if Run has not done
+// so yet.
+func (s *SitemapService) Sitemap(ctx context.Context) (*Sitemap, error) {
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+	if current != nil {
+		return current, nil
+	}
+
+	s.generateMu.Lock()
+	defer s.generateMu.Unlock()
+	s.mu.RLock()
+	current = s.current
+	s.mu.RUnlock()
+	if current != nil {
+		return current, nil
+	}
+	return s.generate(ctx)
+}
+
+// Generate rebuilds the sitemap from every post.
+func (s *SitemapService) Generate(ctx context.Context) (*Sitemap, error) {
+	s.generateMu.Lock()
+	defer s.generateMu.Unlock()
+	return s.generate(ctx)
+}
+
+func (s *SitemapService) generate(ctx context.Context) (*Sitemap, error) {
+	baseURL := strings.TrimSuffix(s.site.BaseURL, "/")
+	result := &Sitemap{Generated: time.Now()}
+	var chunks []sitemap.URL
+	var urls []sitemap.URL
+	var lastMod time.Time
+
+	flush := func() error {
+		data, err := sitemap.URLSet(urls)
+		if err != nil {
+			return err
+		}
+		result.Chunks = append(result.Chunks, data)
+		chunks = append(chunks, sitemap.URL{
+			Loc:     baseURL + fmt.Sprintf(SitemapChunkPath, len(result.Chunks)),
+			LastMod: lastMod,
+		})
+		urls, lastMod = nil, time.Time{}
+		return nil
+	}
+
+	err := s.posts.Each(ctx, func(post *models.Post) error {
+		urls = append(urls, sitemap.URL{
+			Loc:     s.site.PostURL(post.ID.String(), post.Slug()),
+			LastMod: post.UpdatedAt,
+		})
+		if post.UpdatedAt.After(lastMod) {
+			lastMod = post.UpdatedAt
+		}
+		if len(urls) == s.chunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sitemap: %w", err)
+	}
+	// An index must list at least one sitemap, even an empty one.
+	if len(urls) > 0 || len(chunks) == 0 {
+		if err := flush(); err != nil {
+			return nil, fmt.Errorf("failed to generate sitemap: %w", err)
+		}
+	}
+
+	if result.Index, err = sitemap.Index(chunks); err != nil {
+		return nil, fmt.Errorf("failed to generate sitemap: %w", err)
+	}
+
+	s.mu.Lock()
+	s.current = result
+	s.mu.Unlock()
+	return result, nil
+}
+
+// HandlePostEvent schedules a rebuild; Run coalesces bursts of changes into
+// one.
+func (s *SitemapService) HandlePostEvent(ctx context.Context, event PostEvent) {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run generates the sitemap, then rebuilds it delay after posts change and
+// every interval to pick up writes made by other instances. A zero
+// interval disables the periodic rebuild.
+func (s *SitemapService) Run(ctx context.Context, delay, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if _, err := s.Generate(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[WARN] %v", err)
+		}
+
+		select {
+		case <-s.changed:
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			// Changes during the delay are covered by this rebuild.
+			select {
+			case <-s.changed:
+			default:
+			}
+		case <-tick:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/memory"
+	"blog/internal/models"
+	"blog/internal/services"
+
+	"github.com/google/uuid"
+)
+
+func TestSitemapChunks(t *testing.T) {
+	posts := memory.NewPostRepository()
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		posts.Create(context.Background(), &models.Post{
+			ID:        uuid.New(),
+			Title:     "Post",
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+			UpdatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	svc := services.NewSitemapService(posts, &config.SiteConfig{
+		BaseURL:          "https://blog.example/",
+		PostPath:         "/posts/{id}",
+		SitemapChunkSize: 2,
+	})
+	sm, err := svc.Generate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sm.Chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(sm.Chunks))
+	}
+	if n := strings.Count(string(sm.Chunks[0]), "<url>"); n != 2 {
+		t.Errorf("first chunk has %d URLs, want 2", n)
+	}
+	index := string(sm.Index)
+	for _, want := range []string{
+		"<loc>https://blog.example/sitemaps/posts-1.xml</loc><lastmod>2024-05-01T01:00:00Z</lastmod>",
+		"<loc>https://blog.example/sitemaps/posts-2.xml</loc><lastmod>2024-05-01T02:00:00Z</lastmod>",
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index missing %s:\n%s", want, index)
+		}
+	}
+}
+
+func TestSitemapRegeneratesOnPostEvents(t *testing.T) {
+	f := newPostServiceFixture(t)
+	svc := services.NewSitemapService(f.posts, &config.SiteConfig{BaseURL: "https://blog.example", PostPath: "/posts/{id}"})
+	f.events.Subscribe(svc.HandlePostEvent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.Run(ctx, 10*time.Millisecond, 0)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	post, err := f.svc.CreatePost(context.Background(), &models.PostCreateRequest{Title: "Hello", Content: "World"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		sm, err := svc.Sitemap(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(sm.Chunks[0]), post.ID.String()) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sitemap not regenerated:\n%s", sm.Chunks[0])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"blog/internal/database"
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// UnitOfWork runs fn in a transaction. Repository calls and TxHandlers
+// made with the context passed to fn take part in it.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// PostRepository is the system of record for posts. Get and Delete return
+// database.ErrNotFound for unknown IDs and Create returns
+// database.ErrDuplicate for existing ones. Writes conditional on a version
+// return database.ErrStale when the stored post has moved on.
+type PostRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Post, error)
+	ListByTag(ctx context.Context, tag string) ([]models.Post, error)
+	// ListRecent returns the newest posts, only those tagged tag unless it
+	// is empty.
+	ListRecent(ctx context.Context, tag string, limit int) ([]models.Post, error)
+	// Each calls fn for every post, oldest first, stopping at the first
+	// error.
+	Each(ctx context.Context, fn func(post *models.Post) error) error
+	Create(ctx context.Context, post *models.Post) error
+	// Update saves everything but view_count, which is owned by the view
+	// flusher, if the stored version is post.Version, and then increments
+	// post.Version.
+	Update(ctx context.Context, post *models.Post) error
+	// Delete returns the post as it was before deletion. A non-zero version
+	// makes the delete conditional on it.
+	Delete(ctx context.Context, id uuid.UUID, version int64) (*models.Post, error)
+	// AddViews adds views to the post's view count, leaving its version
+	// alone, and returns the post with at least its ID, title and new view
+	// count.
+	AddViews(ctx context.Context, id uuid.UUID, views int64) (*models.Post, error)
+}
+
+// PostCache is a read-through cache of single posts. GetPost returns
+// ErrCacheMiss when the post is not cached and ErrCacheUnavailable while
+// the backend is down.
+type PostCache interface {
+	GetPost(ctx context.Context, id uuid.UUID) (*models.Post, error)
+	SetPost(ctx context.Context, post *models.Post) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
+}
+
+// JSONCache stores JSON-encoded values under arbitrary keys. GetJSON
+// returns ErrCacheMiss and ErrCacheUnavailable like PostCache.GetPost.
+type JSONCache interface {
+	GetJSON(ctx context.Context, key string, dest interface{}) error
+	SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// ViewCounter buffers post views between flushes.
+type ViewCounter interface {
+	RecordPostView(ctx context.Context, id uuid.UUID, day time.Time, visitor string) error
+	PopPostViews(ctx context.Context, count int64) ([]models.PostStats, error)
+	RestorePostViews(ctx context.Context, stats []models.PostStats) error
+}
+
+// PostIndex is the full-text search index over posts.
+type PostIndex interface {
+	IndexPost(ctx context.Context, post *models.Post) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
+	// BulkIndex indexes upserts and removes deletes in one request.
+	BulkIndex(ctx context.Context, upserts []*models.Post, deletes []uuid.UUID) error
+	UpdateViewCount(ctx context.Context, id uuid.UUID, viewCount int64) error
+	SearchPosts(ctx context.Context, req *models.PostSearchRequest) (*models.PostSearchResponse, error)
+}
+
+var (
+	_ UnitOfWork     = (*database.UnitOfWork)(nil)
+	_ PostRepository = (*database.PostRepository)(nil)
+	_ PostCache      = (*CacheService)(nil)
+	_ ViewCounter    = (*CacheService)(nil)
+	_ JSONCache      = (*CacheService)(nil)
+	_ PostIndex      = (*SearchService)(nil)
+	_ PostIndex      = (*BulkIndexer)(nil)
+)
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"blog/internal/database"
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const viewFlushBatchSize = 500
+
+type ViewService struct {
+	uow         UnitOfWork
+	posts       PostRepository
+	stats       *database.PostStatsRepository
+	cache       ViewCounter
+	searchSvc   PostIndex
+	activitySvc *ActivityService
+	trackUnique bool
+}
+
+func NewViewService(uow UnitOfWork, posts PostRepository, stats *database.PostStatsRepository, cache ViewCounter, searchSvc PostIndex, activitySvc *ActivityService, trackUnique bool) *ViewService {
+	return &ViewService{
+		uow:         uow,
+		posts:       posts,
+		stats:       stats,
+		cache:       cache,
+		searchSvc:   searchSvc,
+		activitySvc: activitySvc,
+		trackUnique: trackUnique,
+	}
+}
+
+// RecordView counts a read of the post. Views are only buffered in Redis;
+// they reach PostgreSQL on the next flush and are dropped while Redis is
+// unavailable.
+func (s *ViewService) RecordView(ctx context.Context, postID uuid.UUID, visitor string) error {
+	if !s.trackUnique {
+		visitor = ""
+	}
+	return s.cache.RecordPostView(ctx, postID, time.Now(), visitor)
+}
+
+// Run flushes buffered views every interval until ctx is cancelled, then
+// performs a final flush so counts are not lost on shutdown.
+func (s *ViewService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+				log.Printf("[WARN] Failed to flush post views: %v", err)
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.Flush(flushCtx); err != nil && !errors.Is(err, ErrCacheUnavailable) {
+				log.Printf("[WARN] Failed to flush post views: %v", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+func (s *ViewService) Flush(ctx context.Context) error {
+	for {
+		stats, err := s.cache.PopPostViews(ctx, viewFlushBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+
+		if err := s.flushBatch(ctx, stats); err != nil {
+			if restoreErr := s.cache.RestorePostViews(ctx, stats); restoreErr != nil {
+				log.Printf("[WARN] Lost %d post view counters: %v", len(stats), restoreErr)
+			}
+			return err
+		}
+	}
+}
+
+func (s *ViewService) flushBatch(ctx context.Context, stats []models.PostStats) error {
+	totals := make(map[uuid.UUID]int64)
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		for i := range stats {
+			st := &stats[i]
+			post, err := s.posts.AddViews(ctx, st.PostID, st.Views)
+			if errors.Is(err, database.ErrNotFound) {
+				// The post was deleted after it was viewed.
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update view count: %w", err)
+			}
+			total := post.ViewCount
+			totals[st.PostID] = total
+
+			if err := s.stats.Add(ctx, st); err != nil {
+				return fmt.Errorf("failed to upsert post stats: %w", err)
+			}
+
+			if err := s.activitySvc.LogActivity(ctx, models.ActionViewPost, post, models.FieldChanges{
+				"views": {Old: total - st.Views, New: total},
+			}); err != nil {
+				return fmt.Errorf("failed to log activity: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for id, total := range totals {
+		if err := s.searchSvc.UpdateViewCount(ctx, id, total); err != nil {
+			log.Printf("[WARN] Failed to update view count in Elasticsearch: %v", err)
+		}
+	}
+
+	return nil
+}
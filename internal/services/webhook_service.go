@@ -0,0 +1,318 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/database"
+	"blog/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookPollInterval   = 5 * time.Second
+	webhookBatchSize      = 50
+	webhookTimeout        = 10 * time.Second
+	webhookLeaseMargin    = time.Minute
+	webhookMaxAttempts    = 8
+	webhookBaseBackoff    = 30 * time.Second
+	webhookMaxBackoff     = 6 * time.Hour
+	defaultDeliveryLimit  = 50
+	maxDeliveryLimit      = 200
+	webhookMaxErrorLength = 1024
+
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// webhookLease covers sending a whole batch one delivery after another,
+// each taking up to webhookTimeout.
+const webhookLease = webhookBatchSize*webhookTimeout + webhookLeaseMargin
+
+var ErrWebhookNotFound = newError(ErrNotFound, "webhook_not_found", "webhook not found")
+
+// WebhookService delivers post lifecycle events to registered endpoints.
+// Deliveries are queued in webhook_deliveries inside the transaction that
+// changes the post, so an event is sent if and only if the change commits.
+type WebhookService struct {
+	webhooks *database.WebhookRepository
+	client   *http.Client
+}
+
+func NewWebhookService(webhooks *database.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *models.WebhookCreateRequest) (*models.WebhookCreateResponse, error) {
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := models.Webhook{
+		ID:     uuid.New(),
+		URL:    req.URL,
+		Secret: secret,
+		Events: req.Events,
+		Active: true,
+	}
+	if err := s.webhooks.Create(ctx, &webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &models.WebhookCreateResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := s.webhooks.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	err := s.webhooks.Delete(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID uuid.UUID, query *models.WebhookDeliveryQuery) ([]models.WebhookDelivery, error) {
+	exists, err := s.webhooks.Exists(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	deliveries, err := s.webhooks.ListDeliveries(ctx, webhookID, query.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Enqueue queues the event for every active webhook subscribed to it. It
+// must be called in the unit of work that writes the post.
+func (s *WebhookService) Enqueue(ctx context.Context, event string, post *models.Post) error {
+	webhooks, err := s.webhooks.ListSubscribed(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:         uuid.NewString(),
+		Event:      event,
+		OccurredAt: now,
+		Post:       post.ToResponse(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			Event:         event,
+			PostID:        post.ID,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}
+	}
+
+	if err := s.webhooks.CreateDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (s *WebhookService) HandlePostEvent(ctx context.Context, event PostEvent) error {
+	return s.Enqueue(ctx, event.Action(), event.Subject())
+}
+
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[WARN] Webhook delivery failed: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DeliverDue sends pending deliveries whose next attempt is due. Claimed
+// rows are leased by pushing next_attempt_at forward, so several instances
+// can run the worker without sending the same delivery twice. Deliveries
+// that could not be sent before their lease runs out are left for the next
+// claim.
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	for {
+		leaseEnd := time.Now().Add(webhookLease)
+		due, err := s.webhooks.ClaimDueDeliveries(ctx, webhookBatchSize, leaseEnd)
+		if err != nil {
+			return fmt.Errorf("failed to claim deliveries: %w", err)
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		webhookIDs := make([]uuid.UUID, len(due))
+		for i, d := range due {
+			webhookIDs[i] = d.WebhookID
+		}
+		webhooks, err := s.webhooks.GetMany(ctx, webhookIDs)
+		if err != nil {
+			return fmt.Errorf("failed to load webhooks: %w", err)
+		}
+		byID := make(map[uuid.UUID]models.Webhook, len(webhooks))
+		for _, w := range webhooks {
+			byID[w.ID] = w
+		}
+
+		for i := range due {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if time.Until(leaseEnd) < webhookTimeout+webhookLeaseMargin/2 {
+				// Slow database writes ate into the lease; another claim
+				// may soon pick up the rest.
+				break
+			}
+			webhook, ok := byID[due[i].WebhookID]
+			if !ok {
+				// Deleted while claimed; its deliveries went with it.
+				continue
+			}
+			due[i].Webhook = webhook
+			s.deliver(ctx, &due[i])
+		}
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d *models.WebhookDelivery) {
+	status, err := s.send(ctx, d)
+
+	updates := map[string]interface{}{
+		"attempts":        d.Attempts + 1,
+		"response_status": status,
+	}
+	switch {
+	case err == nil:
+		updates["status"] = models.DeliverySucceeded
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = ""
+	case d.Attempts+1 >= webhookMaxAttempts:
+		updates["status"] = models.DeliveryFailed
+		updates["last_error"] = truncateError(err)
+	default:
+		updates["next_attempt_at"] = time.Now().Add(webhookBackoff(d.Attempts + 1))
+		updates["last_error"] = truncateError(err)
+	}
+
+	if err := s.webhooks.UpdateDelivery(ctx, d.ID, updates); err != nil {
+		log.Printf("[WARN] Failed to record webhook delivery %s: %v", d.ID, err)
+	}
+}
+
+func (s *WebhookService) send(ctx context.Context, d *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-webhooks/1")
+	req.Header.Set(WebhookEventHeader, d.Event)
+	req.Header.Set(WebhookDeliveryHeader, d.ID.String())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(d.Webhook.Secret, timestamp, d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("endpoint responded %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.payload".
+// Subscribers recompute it with their secret and should reject stale
+// timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempt int) time.Duration {
+	backoff := webhookBaseBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > webhookMaxErrorLength {
+		return msg[:webhookMaxErrorLength]
+	}
+	return msg
+}
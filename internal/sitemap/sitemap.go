@@ -0,0 +1,79 @@
+// Package sitemap renders sitemaps and sitemap indexes in the
+// sitemaps.org 0.9 format.
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	ContentType = "application/xml; charset=utf-8"
+	// MaxURLs is the protocol's limit on the URLs in one sitemap and the
+	// sitemaps in one index.
+	MaxURLs = 50000
+
+	namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// URL is a page in a sitemap, or a sitemap in an index. A zero LastMod is
+// omitted.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	URLs    []entry  `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+// URLSet renders a sitemap listing urls.
+func URLSet(urls []URL) ([]byte, error) {
+	if len(urls) > MaxURLs {
+		return nil, fmt.Errorf("sitemap has %d URLs, more than %d", len(urls), MaxURLs)
+	}
+	return marshal(urlSet{XMLNS: namespace, URLs: entries(urls)})
+}
+
+// Index renders a sitemap index listing sitemaps.
+func Index(sitemaps []URL) ([]byte, error) {
+	if len(sitemaps) > MaxURLs {
+		return nil, fmt.Errorf("sitemap index has %d sitemaps, more than %d", len(sitemaps), MaxURLs)
+	}
+	return marshal(index{XMLNS: namespace, Sitemaps: entries(sitemaps)})
+}
+
+func entries(urls []URL) []entry {
+	result := make([]entry, len(urls))
+	for i, u := range urls {
+		result[i] = entry{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			result[i].LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+	}
+	return result
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode sitemap: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelimiter = "---\n"
+
+type markdownWriter struct {
+	zw    *zip.Writer
+	names map[string]bool
+}
+
+// NewMarkdownWriter writes a zip with one <slug>.md file per post.
+func NewMarkdownWriter(w io.Writer) Writer {
+	return &markdownWriter{zw: zip.NewWriter(w), names: make(map[string]bool)}
+}
+
+func (w *markdownWriter) Write(r Record) error {
+	data, err := MarshalMarkdown(r)
+	if err != nil {
+		return err
+	}
+
+	base := r.Slug
+	if base == "" {
+		base = r.ID
+	}
+	name := base + ".md"
+	for i := 2; w.names[name]; i++ {
+		name = base + "-" + strconv.Itoa(i) + ".md"
+	}
+	w.names[name] = true
+
+	f, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: r.UpdatedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *markdownWriter) Close() error { return w.zw.Close() }
+
+// MarshalMarkdown renders a record as YAML front matter followed by the
+// content, byte for byte.
+func MarshalMarkdown(r Record) ([]byte, error) {
+	meta, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal front matter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter)
+	buf.Write(meta)
+	buf.WriteString(frontMatterDelimiter)
+	buf.WriteString("\n")
+	buf.WriteString(r.Content)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMarkdown parses a file written by MarshalMarkdown. Front matter
+// is optional; without it the whole file is the content.
+func UnmarshalMarkdown(data []byte) (Record, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var r Record
+	if rest, ok := strings.CutPrefix(text, frontMatterDelimiter); ok {
+		meta, body, found := strings.Cut(rest, "\n"+frontMatterDelimiter)
+		if !found {
+			return Record{}, fmt.Errorf("%w: unterminated front matter", ErrInvalidFormat)
+		}
+		if err := yaml.Unmarshal([]byte(meta), &r); err != nil {
+			return Record{}, fmt.Errorf("%w: front matter: %v", ErrInvalidFormat, err)
+		}
+		// Only the newline ending the closing delimiter separates it from
+		// the content; everything after it is kept as written.
+		text = strings.TrimPrefix(body, "\n")
+	}
+	r.Content = text
+	return r, nil
+}
+
+// ReadMarkdownZip reads every .md file in the archive, in name order.
+func ReadMarkdownZip(data []byte) ([]Record, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	var records []Record
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || path.Ext(f.Name) != ".md" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFormat, f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxRecordSize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFormat, f.Name, err)
+		}
+		if len(content) > maxRecordSize {
+			return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidFormat, f.Name, maxRecordSize)
+		}
+
+		record, err := UnmarshalMarkdown(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if record.Title == "" {
+			record.Title = strings.TrimSuffix(path.Base(f.Name), ".md")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,47 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const maxRecordSize = 10 << 20
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func NewNDJSONWriter(w io.Writer) Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &ndjsonWriter{enc: enc}
+}
+
+func (w *ndjsonWriter) Write(r Record) error { return w.enc.Encode(r) }
+func (w *ndjsonWriter) Close() error         { return nil }
+
+// ReadNDJSON reads one record per non-empty line.
+func ReadNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRecordSize)
+
+	var records []Record
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFormat, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,78 @@
+// Package transfer reads and writes posts in the formats used to move a
+// blog between systems: NDJSON, a zip of Markdown files with YAML front
+// matter, and WordPress WXR (import only).
+package transfer
+
+import (
+	"errors"
+	"time"
+
+	"blog/internal/models"
+)
+
+const (
+	FormatNDJSON   = "ndjson"
+	FormatMarkdown = "markdown"
+	FormatWXR      = "wxr"
+)
+
+var ErrInvalidFormat = errors.New("invalid import file")
+
+// Record is a post as exported. Imports keep ID and dates when present; a
+// missing ContentFormat, as in files exported before it existed, imports
+// as markdown.
+type Record struct {
+	ID            string    `json:"id,omitempty" yaml:"id,omitempty"`
+	Title         string    `json:"title" yaml:"title"`
+	Slug          string    `json:"slug,omitempty" yaml:"slug,omitempty"`
+	Tags          []string  `json:"tags" yaml:"tags"`
+	ContentFormat string    `json:"content_format,omitempty" yaml:"content_format,omitempty"`
+	CreatedAt     time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" yaml:"updated_at"`
+	Content       string    `json:"content" yaml:"-"`
+}
+
+func FromPost(post *models.Post) Record {
+	tags := []string(post.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return Record{
+		ID:            post.ID.String(),
+		Title:         post.Title,
+		Slug:          post.Slug(),
+		Tags:          tags,
+		CreatedAt:     post.CreatedAt.UTC(),
+		UpdatedAt:     post.UpdatedAt.UTC(),
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+	}
+}
+
+// Operation is the bulk create that imports the record. Posts have no slug
+// column, so Slug is not kept.
+func (r Record) Operation() models.BulkOperation {
+	op := models.BulkOperation{
+		Op:      models.BulkCreate,
+		ID:      r.ID,
+		Title:   &r.Title,
+		Content: &r.Content,
+		Tags:    r.Tags,
+	}
+	if r.ContentFormat != "" {
+		op.ContentFormat = &r.ContentFormat
+	}
+	if !r.CreatedAt.IsZero() {
+		op.CreatedAt = &r.CreatedAt
+	}
+	if !r.UpdatedAt.IsZero() {
+		op.UpdatedAt = &r.UpdatedAt
+	}
+	return op
+}
+
+// Writer writes an export one post at a time.
+type Writer interface {
+	Write(r Record) error
+	Close() error
+}
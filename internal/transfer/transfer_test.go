@@ -0,0 +1,179 @@
+package transfer_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/transfer"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func samplePost() *models.Post {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	return &models.Post{
+		ID:        uuid.New(),
+		Title:     "Tiêu đề: Go & Redis",
+		Content:   "# Heading\n\n---\n\nBody with a <b>tag</b>.",
+		Tags:      pq.StringArray{"go", "redis"},
+		CreatedAt: created,
+		UpdatedAt: created.Add(time.Hour),
+	}
+}
+
+func TestFromPostSlug(t *testing.T) {
+	if got := transfer.FromPost(samplePost()).Slug; got != "tieu-de-go-redis" {
+		t.Errorf("slug = %q, want tieu-de-go-redis", got)
+	}
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	want := transfer.FromPost(samplePost())
+
+	var buf bytes.Buffer
+	w := transfer.NewNDJSONWriter(&buf)
+	w.Write(want)
+	w.Write(want)
+	w.Close()
+
+	got, err := transfer.ReadNDJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadNDJSON: %v", err)
+	}
+	if len(got) != 2 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("read %+v, want two of %+v", got, want)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	post := samplePost()
+	want := transfer.FromPost(post)
+
+	var buf bytes.Buffer
+	w := transfer.NewMarkdownWriter(&buf)
+	w.Write(want)
+	w.Write(want)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := transfer.ReadMarkdownZip(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadMarkdownZip: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("read %d records, want 2 (one per file, despite equal slugs)", len(got))
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("read %+v\nwant %+v", got[0], want)
+	}
+}
+
+func TestUnmarshalMarkdownWithoutFrontMatter(t *testing.T) {
+	r, err := transfer.UnmarshalMarkdown([]byte("Just text\n"))
+	if err != nil {
+		t.Fatalf("UnmarshalMarkdown: %v", err)
+	}
+	if r.Content != "Just text\n" || r.Title != "" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestMarkdownRoundTripKeepsTrailingNewlines(t *testing.T) {
+	for _, content := range []string{"", "No newline", "One newline\n", "Two newlines\n\n", "\nLeading and trailing\n\n\n"} {
+		want := transfer.Record{Title: "T", Content: content}
+		data, err := transfer.MarshalMarkdown(want)
+		if err != nil {
+			t.Fatalf("MarshalMarkdown: %v", err)
+		}
+		got, err := transfer.UnmarshalMarkdown(data)
+		if err != nil {
+			t.Fatalf("UnmarshalMarkdown: %v", err)
+		}
+		if got.Content != content {
+			t.Errorf("content %q read back as %q", content, got.Content)
+		}
+	}
+}
+
+const wxr = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<link>https://blog.example.com</link>
+	<wp:category><wp:category_nicename>news</wp:category_nicename></wp:category>
+	<item>
+		<title>Hello WordPress</title>
+		<guid isPermaLink="false">https://blog.example.com/?p=1</guid>
+		<content:encoded><![CDATA[<p>Welcome</p>]]></content:encoded>
+		<excerpt:encoded><![CDATA[Not the content]]></excerpt:encoded>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_date>2019-05-06 09:10:11</wp:post_date>
+		<wp:post_date_gmt>2019-05-06 07:10:11</wp:post_date_gmt>
+		<wp:post_modified_gmt>2020-01-01 00:00:00</wp:post_modified_gmt>
+		<wp:post_name>hello-wordpress</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="uncategorized"><![CDATA[Uncategorized]]></category>
+		<category domain="category" nicename="news"><![CDATA[News]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[Go]]></category>
+	</item>
+	<item>
+		<title>Draft</title>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>About</title>
+		<wp:status>publish</wp:status>
+		<wp:post_type>page</wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestReadWXRRestoresParagraphs(t *testing.T) {
+	content := "First line\nsecond line\n\n<ul>\n<li>item</li>\n</ul>\n\n<pre>code\n\nmore code</pre>\n\nLast"
+	doc := strings.Replace(wxr, "<p>Welcome</p>", content, 1)
+
+	records, err := transfer.ReadWXR(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ReadWXR: %v", err)
+	}
+	want := "<p>First line<br />\nsecond line</p>\n\n<ul>\n<li>item</li>\n</ul>\n\n<pre>code\n\nmore code</pre>\n\n<p>Last</p>"
+	if got := records[0].Content; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
+
+func TestReadWXR(t *testing.T) {
+	records, err := transfer.ReadWXR(strings.NewReader(wxr))
+	if err != nil {
+		t.Fatalf("ReadWXR: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("read %d records, want only the published post", len(records))
+	}
+
+	r := records[0]
+	if r.Title != "Hello WordPress" || r.Content != "<p>Welcome</p>" || r.Slug != "hello-wordpress" {
+		t.Errorf("record = %+v", r)
+	}
+	if !reflect.DeepEqual(r.Tags, []string{"news", "go"}) {
+		t.Errorf("tags = %v, want [news go]", r.Tags)
+	}
+	if want := time.Date(2019, 5, 6, 7, 10, 11, 0, time.UTC); !r.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", r.CreatedAt, want)
+	}
+
+	again, _ := transfer.ReadWXR(strings.NewReader(wxr))
+	if again[0].ID != r.ID {
+		t.Error("IDs differ between imports of the same file")
+	}
+}
@@ -0,0 +1,147 @@
+package transfer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"blog/internal/markup"
+
+	"github.com/google/uuid"
+)
+
+const wxrTimeLayout = "2006-01-02 15:04:05"
+
+type wxrDocument struct {
+	Channel struct {
+		Link  string    `xml:"link"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title           string `xml:"title"`
+	GUID            string `xml:"guid"`
+	Content         string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID          string `xml:"post_id"`
+	PostName        string `xml:"post_name"`
+	PostType        string `xml:"post_type"`
+	Status          string `xml:"status"`
+	PostDate        string `xml:"post_date"`
+	PostDateGMT     string `xml:"post_date_gmt"`
+	PostModifiedGMT string `xml:"post_modified_gmt"`
+	Categories      []struct {
+		Domain   string `xml:"domain,attr"`
+		Nicename string `xml:"nicename,attr"`
+	} `xml:"category"`
+}
+
+// ReadWXR reads the published posts of a WordPress export. Pages,
+// attachments, drafts and trashed posts are skipped. IDs are derived from
+// each item's GUID, so importing the same file twice conflicts instead of
+// duplicating posts. Categories and tags both become tags, and content is
+// imported as HTML after restoring the paragraphs WordPress only adds when
+// displaying a post.
+func ReadWXR(r io.Reader) ([]Record, error) {
+	var doc wxrDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	var records []Record
+	for _, item := range doc.Channel.Items {
+		if item.PostType != "post" || item.Status != "publish" {
+			continue
+		}
+
+		key := item.GUID
+		if key == "" {
+			key = doc.Channel.Link + "?p=" + item.PostID
+		}
+
+		tags := []string{}
+		for _, c := range item.Categories {
+			if (c.Domain == "post_tag" || c.Domain == "category") && c.Nicename != "" && c.Nicename != "uncategorized" && !slices.Contains(tags, c.Nicename) {
+				tags = append(tags, c.Nicename)
+			}
+		}
+
+		createdAt := parseWXRTime(item.PostDateGMT)
+		if createdAt.IsZero() {
+			createdAt = parseWXRTime(item.PostDate)
+		}
+		updatedAt := parseWXRTime(item.PostModifiedGMT)
+		if updatedAt.IsZero() {
+			updatedAt = createdAt
+		}
+
+		records = append(records, Record{
+			ID:            uuid.NewSHA1(uuid.NameSpaceURL, []byte(key)).String(),
+			Title:         strings.TrimSpace(item.Title),
+			Slug:          item.PostName,
+			Tags:          tags,
+			ContentFormat: markup.HTML,
+			CreatedAt:     createdAt,
+			UpdatedAt:     updatedAt,
+			Content:       autop(strings.TrimSpace(item.Content)),
+		})
+	}
+	return records, nil
+}
+
+// parseWXRTime reads WordPress's "2006-01-02 15:04:05" dates as UTC. Unset
+// dates are written as all zeroes and come back as the zero time.
+func parseWXRTime(value string) time.Time {
+	t, err := time.Parse(wxrTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// autopBlock matches chunks that open with a block-level element or an
+// HTML comment, such as the block editor's <!-- wp:paragraph -->, and so
+// are not wrapped in a paragraph.
+var autopBlock = regexp.MustCompile(`(?i)^<(!--|/?(p|div|h[1-6]|ul|ol|li|dl|dt|dd|table|thead|tbody|tfoot|tr|td|th|caption|blockquote|pre|figure|figcaption|hr|address|section|article|aside|header|footer|nav|form|details|summary)\b)`)
+
+var autopBreak = regexp.MustCompile(`\n[ \t]*\n`)
+
+// autop is a minimal version of WordPress's wpautop: classic editor content
+// separates paragraphs with blank lines and lines with single newlines.
+// Chunks that already start with a block-level element and the contents of
+// <pre> elements are left as they are.
+func autop(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var b strings.Builder
+	inPre := false
+	for _, chunk := range autopBreak.Split(content, -1) {
+		chunk = strings.TrimSpace(chunk)
+		if chunk == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+
+		lower := strings.ToLower(chunk)
+		opens := strings.Count(lower, "<pre")
+		closes := strings.Count(lower, "</pre>")
+		switch {
+		case inPre, autopBlock.MatchString(chunk):
+			b.WriteString(chunk)
+		default:
+			b.WriteString("<p>")
+			b.WriteString(strings.ReplaceAll(chunk, "\n", "<br />\n"))
+			b.WriteString("</p>")
+		}
+		if opens != closes {
+			inPre = opens > closes
+		}
+	}
+	return b.String()
+}
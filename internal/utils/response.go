@@ -1,14 +1,49 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"blog/internal/requestmeta"
+
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+const ProblemContentType = "application/problem+json"
+
 type Response struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+}
+
+// Problem is an RFC 7807 error body. Code is a stable, machine-readable
+// identifier of the error; clients should branch on it rather than on
+// Title or Detail.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// coder is implemented by errors that carry their own problem code.
+type coder interface {
+	Code() string
 }
 
 func SuccessResponse(c *gin.Context, statusCode int, message string, data interface{}) {
@@ -19,15 +54,100 @@ func SuccessResponse(c *gin.Context, statusCode int, message string, data interf
 	})
 }
 
+// ErrorResponse writes a problem+json body. For client errors the error
+// text is returned as the detail; for server errors it is only logged, and
+// the detail is message.
 func ErrorResponse(c *gin.Context, statusCode int, message string, err error) {
-	response := Response{
-		Success: false,
-		Message: message,
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Code:      ErrorCode(statusCode, err),
+		Detail:    message,
+		Instance:  c.Request.URL.Path,
+		RequestID: requestmeta.FromContext(c.Request.Context()).RequestID,
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		if err != nil {
+			log.Printf("[ERROR] request_id=%s %s %s: %s: %v", problem.RequestID, c.Request.Method, c.Request.URL.Path, message, err)
+		}
+	} else if fields := fieldErrors(err); fields != nil {
+		problem.Code = "validation_failed"
+		problem.Errors = fields
+	} else if err != nil {
+		if message != "" {
+			problem.Detail = message + ": " + err.Error()
+		} else {
+			problem.Detail = err.Error()
+		}
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(statusCode, problem)
+}
+
+// ErrorCode is the stable problem code for err: its own code if it has one,
+// otherwise one derived from the status.
+func ErrorCode(statusCode int, err error) string {
+	var coded coder
+	if errors.As(err, &coded) {
+		return coded.Code()
 	}
-	
-	if err != nil {
-		response.Error = err.Error()
+	return codeForStatus(statusCode)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusPreconditionFailed:
+		return "precondition_failed"
+	case http.StatusPreconditionRequired:
+		return "precondition_required"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal_error"
 	}
-	
-	c.JSON(statusCode, response)
-}
\ No newline at end of file
+	return "request_failed"
+}
+
+// fieldErrors extracts per-field details from binding errors, or returns
+// nil for any other error.
+func fieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: validationMessage(fe),
+			}
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be of type " + typeErr.Type.String(),
+		}}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorResponseValidationDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		Title string `json:"title" binding:"required"`
+		Sort  string `json:"sort" binding:"omitempty,oneof=recent popular"`
+	}
+
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"sort":"oldest"}`)))
+
+	var problem utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Code != "validation_failed" {
+		t.Fatalf("problem = %+v", problem)
+	}
+
+	got := map[string]string{}
+	for _, fe := range problem.Errors {
+		got[fe.Field] = fe.Code
+	}
+	if got["title"] != "required" || got["sort"] != "oneof" {
+		t.Errorf("field errors = %+v", problem.Errors)
+	}
+}
+
+func TestErrorResponseTypeMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		var req struct {
+			Tags []string `json:"tags"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"tags":"go"}`)))
+
+	var problem utils.Problem
+	json.Unmarshal(rec.Body.Bytes(), &problem)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "tags" {
+		t.Errorf("problem = %+v", problem)
+	}
+}